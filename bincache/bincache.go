@@ -0,0 +1,50 @@
+// package bincache 为数据目录中的每个<code>.csv生成二进制缓存<code>.stkb。
+// 缓存比csv旧或读取选项不同时才重新生成; readr.Load及readr.OpenScanner会自动使用新的缓存。
+package bincache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"stockstat/readr"
+	"strings"
+)
+
+// Run 更新目录root中各csv文件的缓存, compress为true时压缩缓存。
+// 不是行情数据的csv(如stocklist.csv)被跳过; 有文件失败时返回错误。
+func Run(root string, compress bool) error {
+	ctx := context.Background()
+	opts := &readr.Options{Header: true}
+	updated, skipped, failed := 0, 0, 0
+	err := filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if f == nil {
+			return err
+		}
+		if f.IsDir() || !strings.EqualFold(filepath.Ext(f.Name()), ".csv") {
+			return nil
+		}
+		ok, err := readr.UpdateCache(ctx, path, opts, compress)
+		if _, notPrice := err.(*readr.SchemaError); notPrice { // 如stocklist.csv
+			skipped++
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed++
+			return nil
+		}
+		if ok {
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d updated, %d skipped, %d failed\n", updated, skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d files failed", failed)
+	}
+	return nil
+}
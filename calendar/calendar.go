@@ -0,0 +1,441 @@
+// package calendar 是沪深A股的交易日历。
+//
+// 交易日为周一至周五中不是节假日的日子。节假日来自随程序编入的holidays.txt,
+// 可由数据目录中的holidays.txt补充(见Open); 在行情覆盖的日期范围内,
+// 则以全市场实际有交易的日期为准(见AddTradingDays), 从而可以区分停牌与休市。
+// 节假日文件没有列出的年份可以由行情推断交易日(见InferTradingDays), 否则只能假定周一至周五都交易。
+//
+// 日期一律按年月日处理, 忽略时刻及时区。
+package calendar
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HolidayFile 是数据目录中节假日文件的文件名
+const HolidayFile = "holidays.txt"
+
+// dateLayout 是节假日文件中日期的格式
+const dateLayout = "2006-01-02"
+
+//go:embed holidays.txt
+var bundled string
+
+// day 是自1970-01-01起的天数
+type day int
+
+func toDay(t time.Time) day {
+	y, m, d := t.Date()
+	return day(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+func (d day) time() time.Time {
+	return time.Unix(int64(d)*86400, 0).UTC()
+}
+
+func (d day) weekday() bool {
+	wd := d.time().Weekday()
+	return wd != time.Saturday && wd != time.Sunday
+}
+
+func (d day) year() int {
+	return d.time().Year()
+}
+
+// span 是一段日期, 含两端
+type span struct {
+	from, to day
+}
+
+// Calendar 是交易日历, 零值为周一至周五都交易
+type Calendar struct {
+	holidays map[day]bool
+	years    map[int]bool // 列出了节假日的年份
+	trading  map[day]bool // 由行情得到的交易日
+	from, to day          // trading覆盖的范围, trading为空时无效
+	inferred map[day]bool // 由InferTradingDays得到的交易日
+	spans    map[int]span // inferred在各年中覆盖的范围
+}
+
+// New 返回没有节假日的日历
+func New() *Calendar {
+	return &Calendar{}
+}
+
+// Default 返回含编入的节假日的日历
+func Default() *Calendar {
+	c := New()
+	if err := c.ReadHolidays(strings.NewReader(bundled)); err != nil {
+		panic("calendar: bad bundled holidays: " + err.Error())
+	}
+	return c
+}
+
+// Open 返回含编入的节假日及数据目录dir中holidays.txt(如果有)的日历
+func Open(dir string) (*Calendar, error) {
+	c := Default()
+	err := c.LoadHolidays(filepath.Join(dir, HolidayFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadHolidays 读取节假日文件path
+func (c *Calendar) LoadHolidays(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := c.ReadHolidays(f); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// ReadHolidays 读取节假日, 每行一个日期或"from..to", '#'开始注释
+func (c *Calendar) ReadHolidays(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		from, to := text, text
+		if i := strings.Index(text, ".."); i >= 0 {
+			from, to = strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+2:])
+		}
+		a, err := time.Parse(dateLayout, from)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		b, err := time.Parse(dateLayout, to)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		if b.Before(a) {
+			return fmt.Errorf("line %d: %s after %s", line, from, to)
+		}
+		c.AddHoliday(a, b)
+	}
+	return scanner.Err()
+}
+
+// AddHoliday 把from至to(含两端)记为休市, 所在的年份随之算作已列出节假日(见Covered)
+func (c *Calendar) AddHoliday(from, to time.Time) {
+	if c.holidays == nil {
+		c.holidays = make(map[day]bool)
+		c.years = make(map[int]bool)
+	}
+	for d := toDay(from); d <= toDay(to); d++ {
+		c.holidays[d] = true
+		c.years[d.year()] = true
+	}
+}
+
+// Covered 报告是否列出了year年的节假日
+func (c *Calendar) Covered(year int) bool {
+	return c.years[year]
+}
+
+// AddTradingDays 记录实际有交易的日期, 通常是全市场各股票日期的并集。
+// 此后在这些日期覆盖的范围内, 只有记录过的日期是交易日。
+func (c *Calendar) AddTradingDays(dates []time.Time) {
+	if len(dates) == 0 {
+		return
+	}
+	if c.trading == nil {
+		c.trading = make(map[day]bool)
+		c.from, c.to = toDay(dates[0]), toDay(dates[0])
+	}
+	for _, t := range dates {
+		d := toDay(t)
+		c.trading[d] = true
+		if d < c.from {
+			c.from = d
+		}
+		if d > c.to {
+			c.to = d
+		}
+	}
+}
+
+// InferTradingDays 记录行情中有交易的日期, 但只用于没有列出节假日的年份(见Covered):
+// 这些年份中在记录的第一天与最后一天之间, 只有记录过的日期是交易日。
+// 与AddTradingDays不同, 已列出节假日的年份仍按节假日判断。
+func (c *Calendar) InferTradingDays(dates []time.Time) {
+	for _, t := range dates {
+		d := toDay(t)
+		y := d.year()
+		if c.years[y] {
+			continue
+		}
+		if c.inferred == nil {
+			c.inferred = make(map[day]bool)
+			c.spans = make(map[int]span)
+		}
+		c.inferred[d] = true
+		s, ok := c.spans[y]
+		if !ok {
+			s = span{d, d}
+		}
+		if d < s.from {
+			s.from = d
+		}
+		if d > s.to {
+			s.to = d
+		}
+		c.spans[y] = s
+	}
+}
+
+func (c *Calendar) isTrading(d day) bool {
+	if c.trading != nil && c.from <= d && d <= c.to {
+		return c.trading[d]
+	}
+	if s, ok := c.spans[d.year()]; ok && s.from <= d && d <= s.to {
+		return c.inferred[d]
+	}
+	return d.weekday() && !c.holidays[d]
+}
+
+// IsTradingDay 报告t是否是交易日
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	return c.isTrading(toDay(t))
+}
+
+// maxClosed 是连续休市的最长天数, 用于防止死循环
+const maxClosed = 366
+
+// Next 返回t之后(不含t)的第一个交易日
+func (c *Calendar) Next(t time.Time) time.Time {
+	d := toDay(t) + 1
+	for i := 0; i < maxClosed && !c.isTrading(d); i++ {
+		d++
+	}
+	return d.time()
+}
+
+// Prev 返回t之前(不含t)的最后一个交易日
+func (c *Calendar) Prev(t time.Time) time.Time {
+	d := toDay(t) - 1
+	for i := 0; i < maxClosed && !c.isTrading(d); i++ {
+		d--
+	}
+	return d.time()
+}
+
+// Count 返回from至to(含两端)的交易日数, to早于from时返回0
+func (c *Calendar) Count(from, to time.Time) int {
+	n := 0
+	for d := toDay(from); d <= toDay(to); d++ {
+		if c.isTrading(d) {
+			n++
+		}
+	}
+	return n
+}
+
+// Between 返回a, b之间(不含两端)的交易日数, 即相邻两行之间缺少的交易日数
+func (c *Calendar) Between(a, b time.Time) int {
+	if !b.After(a) {
+		return 0
+	}
+	return c.Count(toDay(a).time().AddDate(0, 0, 1), toDay(b).time().AddDate(0, 0, -1))
+}
+
+// TradingDays 返回from至to(含两端)的各交易日
+func (c *Calendar) TradingDays(from, to time.Time) []time.Time {
+	var days []time.Time
+	for d := toDay(from); d <= toDay(to); d++ {
+		if c.isTrading(d) {
+			days = append(days, d.time())
+		}
+	}
+	return days
+}
+
+// Holidays 返回from至to(含两端)中休市的工作日
+func (c *Calendar) Holidays(from, to time.Time) []time.Time {
+	var days []time.Time
+	for d := toDay(from); d <= toDay(to); d++ {
+		if d.weekday() && !c.isTrading(d) {
+			days = append(days, d.time())
+		}
+	}
+	return days
+}
+
+// Period 是分组的周期
+type Period int
+
+const (
+	Day     Period = iota // 每个交易日一组
+	Week                  // 按ISO周分组
+	Month                 // 按自然月分组
+	Quarter               // 按季度分组
+	Year                  // 按年分组
+)
+
+func (p Period) String() string {
+	switch p {
+	case Week:
+		return "week"
+	case Month:
+		return "month"
+	case Quarter:
+		return "quarter"
+	case Year:
+		return "year"
+	}
+	return "day"
+}
+
+// ParsePeriod 解析day, week, month, quarter或year(也接受d, w, m, q, y)
+func ParsePeriod(s string) (Period, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "day", "d", "daily":
+		return Day, nil
+	case "week", "w", "weekly":
+		return Week, nil
+	case "month", "m", "monthly":
+		return Month, nil
+	case "quarter", "q", "quarterly":
+		return Quarter, nil
+	case "year", "y", "yearly":
+		return Year, nil
+	}
+	return Day, fmt.Errorf("unknown period %q, want day, week, month, quarter or year", s)
+}
+
+// Bounds 返回t所在周期的第一天及最后一天(自然日)
+func Bounds(t time.Time, p Period) (first, last time.Time) {
+	y, m, d := t.Date()
+	t = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	switch p {
+	case Week:
+		first = t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7)) // 周一
+		return first, first.AddDate(0, 0, 6)
+	case Month:
+		first = time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		return first, first.AddDate(0, 1, -1)
+	case Quarter:
+		first = time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, time.UTC)
+		return first, first.AddDate(0, 3, -1)
+	case Year:
+		first = time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return first, first.AddDate(1, 0, -1)
+	}
+	return t, t
+}
+
+// Group 是dates中属于同一周期的一段, dates[Lo:Hi]
+type Group struct {
+	Start  time.Time // 周期的第一天(自然日)
+	Lo, Hi int
+	// Full 为true时这一段包含了该周期的全部交易日
+	Full bool
+}
+
+// Len 返回该段的行数
+func (g *Group) Len() int {
+	return g.Hi - g.Lo
+}
+
+// Group 把升序的dates按周期p分组
+func (c *Calendar) Group(dates []time.Time, p Period) []Group {
+	var groups []Group
+	for lo := 0; lo < len(dates); {
+		first, last := Bounds(dates[lo], p)
+		hi := lo + 1
+		for hi < len(dates) && toDay(dates[hi]) <= toDay(last) {
+			hi++
+		}
+		g := Group{Start: first, Lo: lo, Hi: hi}
+		g.Full = c.countIn(dates[lo:hi]) == c.Count(first, last)
+		groups = append(groups, g)
+		lo = hi
+	}
+	return groups
+}
+
+// GroupDays 从dates的第一天起, 把升序的dates每n个交易日分为一组。
+// 各组按日历的交易日而不是行数划分, 停牌缺少的行不会使其后的组错位;
+// 没有数据的组被略去。
+func (c *Calendar) GroupDays(dates []time.Time, n int) []Group {
+	if n < 1 {
+		n = 1
+	}
+	var groups []Group
+	first := day(0)
+	if len(dates) > 0 {
+		first = toDay(dates[0])
+	}
+	for lo := 0; lo < len(dates); {
+		last := first
+		for k := 1; k < n; k++ {
+			last = toDay(c.Next(last.time()))
+		}
+		if toDay(dates[lo]) <= last {
+			hi := lo + 1
+			for hi < len(dates) && toDay(dates[hi]) <= last {
+				hi++
+			}
+			g := Group{Start: first.time(), Lo: lo, Hi: hi}
+			g.Full = c.countIn(dates[lo:hi]) == c.Count(first.time(), last.time())
+			groups = append(groups, g)
+			lo = hi
+		}
+		first = toDay(c.Next(last.time()))
+	}
+	return groups
+}
+
+// countIn 返回dates中不重复的交易日数
+func (c *Calendar) countIn(dates []time.Time) int {
+	seen := make(map[day]bool, len(dates))
+	for _, t := range dates {
+		if d := toDay(t); c.isTrading(d) {
+			seen[d] = true
+		}
+	}
+	return len(seen)
+}
+
+// sortDays 返回升序且不重复的日期
+func sortDays(set map[day]bool) []time.Time {
+	days := make([]day, 0, len(set))
+	for d := range set {
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+	res := make([]time.Time, len(days))
+	for i, d := range days {
+		res[i] = d.time()
+	}
+	return res
+}
+
+// Union 返回各日期序列的并集, 升序且不重复, 用于AddTradingDays
+func Union(lists ...[]time.Time) []time.Time {
+	set := make(map[day]bool)
+	for _, list := range lists {
+		for _, t := range list {
+			set[toDay(t)] = true
+		}
+	}
+	return sortDays(set)
+}
@@ -0,0 +1,99 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dates(ss ...string) []time.Time {
+	ts := make([]time.Time, len(ss))
+	for i, s := range ss {
+		ts[i] = date(s)
+	}
+	return ts
+}
+
+func TestBundledCoverage(t *testing.T) {
+	c := Default()
+	for y := 2014; y <= 2026; y++ {
+		if !c.Covered(y) {
+			t.Errorf("bundled holidays do not cover %d", y)
+		}
+	}
+	tests := []struct {
+		day     string
+		trading bool
+	}{
+		{"2014-01-30", true},
+		{"2014-02-03", false}, // 春节
+		{"2014-02-07", true},
+		{"2015-09-03", false},
+		{"2025-01-28", false},
+		{"2026-02-23", false},
+		{"2026-02-24", true},
+		{"2026-10-08", true},
+	}
+	for _, tt := range tests {
+		if got := c.IsTradingDay(date(tt.day)); got != tt.trading {
+			t.Errorf("IsTradingDay(%s) = %v, want %v", tt.day, got, tt.trading)
+		}
+	}
+	// 国庆所在的周只有10月8日、9日交易, 有这两天的数据即为完整的一周
+	groups := c.Group(dates("2014-10-08", "2014-10-09", "2014-10-10"), Week)
+	if len(groups) != 1 || !groups[0].Full {
+		t.Errorf("2014 National Day week: %+v, want one full week", groups)
+	}
+}
+
+func TestInferTradingDays(t *testing.T) {
+	// 2004年春节1月19日至28日休市, 编入的节假日没有2004年
+	data := dates(
+		"2004-01-12", "2004-01-13", "2004-01-14", "2004-01-15", "2004-01-16",
+		"2004-01-29", "2004-01-30",
+		"2004-02-02", "2004-02-03",
+	)
+	c := Default()
+	if c.Covered(2004) {
+		t.Fatal("bundled holidays cover 2004")
+	}
+	if g := c.Group(data[5:7], Week); g[0].Full {
+		t.Errorf("week of 2004-01-26 is full before inference")
+	}
+	c.InferTradingDays(data)
+	tests := []struct {
+		day     string
+		trading bool
+	}{
+		{"2004-01-16", true},
+		{"2004-01-19", false},
+		{"2004-01-28", false},
+		{"2004-01-29", true},
+		{"2004-02-04", true}, // 推断的范围之后仍假定周一至周五交易
+		{"2004-01-17", false},
+	}
+	for _, tt := range tests {
+		if got := c.IsTradingDay(date(tt.day)); got != tt.trading {
+			t.Errorf("IsTradingDay(%s) = %v, want %v", tt.day, got, tt.trading)
+		}
+	}
+	if g := c.Group(data[5:7], Week); len(g) != 1 || !g[0].Full {
+		t.Errorf("week of 2004-01-26: %+v, want one full week", g)
+	}
+	if n := c.Between(date("2004-01-16"), date("2004-01-29")); n != 0 {
+		t.Errorf("Between over Spring Festival = %d, want 0", n)
+	}
+
+	// 已列出节假日的年份不受影响, 个别日期缺少数据不算休市
+	c.InferTradingDays(dates("2014-03-03", "2014-03-05"))
+	if !c.IsTradingDay(date("2014-03-04")) {
+		t.Errorf("2014-03-04 inferred as closed in a covered year")
+	}
+}
@@ -0,0 +1,499 @@
+// package catalog 记录数据目录中有哪些数据文件: 每只股票的文件格式, 起止日期, 行数,
+// 最后的权值, 校验和及修改时间, 保存在数据目录的manifest.csv中:
+//
+//	#v1,code,file,format,first,last,rows,power,sha256,size,mtime,error
+//	600000.SH,600000.csv,csv,1999-11-10,2018-07-20,4360,1,9f86d0...,201532,2018-07-21T08:00:00+08:00,
+//
+// 用Build扫描数据目录, Load读取已有的清单, 用Usable等查询哪些文件可用。
+package catalog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"stockstat/calendar"
+	"stockstat/readr"
+	"stockstat/safefile"
+	"stockstat/stockcode"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ManifestName 是清单在数据目录中的文件名
+const ManifestName = "manifest.csv"
+
+// Version 是清单的格式版本
+const Version = 1
+
+// DefaultMaxLag 是缺省的最大滞后天数, 见Catalog.Stale
+const DefaultMaxLag = 30
+
+var columns = []string{"code", "file", "format", "first", "last", "rows", "power", "sha256", "size", "mtime", "error"}
+
+// Entry 是一个数据文件的记录
+type Entry struct {
+	Code      stockcode.Code
+	File      string // 相对于数据目录的文件名
+	Format    readr.Format
+	First     time.Time // 第一天, 没有数据时为零值
+	Last      time.Time // 最后一天
+	Rows      int
+	LastPower float64 // 最后的权值, 没有权值列时为0
+	Checksum  string  // 文件内容的sha256
+	Size      int64
+	ModTime   time.Time
+	Err       string // 读取文件时的错误, 为空表示没有错误
+}
+
+// Empty 报告文件是否没有可用的数据
+func (e *Entry) Empty() bool {
+	return e.Rows == 0 || e.Err != ""
+}
+
+// Catalog 是数据目录的清单
+type Catalog struct {
+	Root    string // 数据目录
+	Entries []Entry
+	// MaxLag 是最后一天比全部文件中最新的一天早多少天以上算作过时, 0表示DefaultMaxLag
+	MaxLag int
+}
+
+// ManifestPath 返回数据目录root中清单的路径
+func ManifestPath(root string) string {
+	return filepath.Join(root, ManifestName)
+}
+
+// dataFile 报告name是否可能是数据文件, 并返回读取它的选项
+func dataFile(name string) (*readr.Options, bool) {
+	if strings.HasPrefix(name, ".") {
+		return nil, false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return &readr.Options{Header: true, NoCache: true}, true
+	case "": // csv2table写出的表格文件或凤凰网的JSON(如stat/600000), 由内容判断
+		return &readr.Options{NoCache: true}, true
+	case ".json", ".txt":
+		return &readr.Options{Format: readr.FormatIfeng, NoCache: true}, true
+	}
+	return nil, false
+}
+
+// fileCode 返回数据文件name对应的股票代码。数据文件以6位数字命名(见stockcode.Code.Number),
+// 其他文件(如stat/6.txt)即使stockcode.Parse能补0解析也不是数据文件。
+func fileCode(name string) (stockcode.Code, bool) {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	if len(base) != 6 || strings.Trim(base, "0123456789") != "" {
+		return stockcode.Code{}, false
+	}
+	code, err := stockcode.Parse(base)
+	return code, err == nil
+}
+
+// Build 扫描数据目录root(不含子目录), 最多同时读workers个文件。
+// 文件名不是6位股票代码的文件(如stocklist.csv, 6.txt)被忽略; 无法读取的文件记录在Entry.Err中。
+func Build(ctx context.Context, root string, workers int) (*Catalog, error) {
+	dir, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	c := &Catalog{Root: root}
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		limit = make(chan struct{}, workers)
+	)
+	for _, d := range dir {
+		name := d.Name()
+		opts, ok := dataFile(name)
+		if !ok || d.IsDir() {
+			continue
+		}
+		code, ok := fileCode(name)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			e := scan(ctx, root, name, opts)
+			e.Code = code
+			mu.Lock()
+			c.Entries = append(c.Entries, e)
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.sort()
+	return c, nil
+}
+
+// UnionDates 返回数据目录root中全部数据文件日期的并集, 升序且不重复,
+// 可作为实际的交易日传给calendar.Calendar.AddTradingDays。
+func UnionDates(ctx context.Context, root string, workers int) ([]time.Time, error) {
+	dir, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		limit = make(chan struct{}, workers)
+		lists [][]time.Time
+	)
+	for _, d := range dir {
+		name := d.Name()
+		opts, ok := dataFile(name)
+		if !ok || d.IsDir() {
+			continue
+		}
+		if _, ok := fileCode(name); !ok {
+			continue
+		}
+		opts.NoCache, opts.Lenient = false, true
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			s, err := readr.OpenScanner(ctx, path, opts)
+			if err != nil {
+				return
+			}
+			defer s.Close()
+			var dates []time.Time
+			for s.Scan() {
+				dates = append(dates, s.Bar().Time)
+			}
+			mu.Lock()
+			lists = append(lists, dates)
+			mu.Unlock()
+		}(filepath.Join(root, name))
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return calendar.Union(lists...), nil
+}
+
+// OpenCalendar 返回数据目录root的交易日历(见calendar.Open),
+// 节假日文件没有列出的年份按root中全部数据文件的日期推断交易日, 见calendar.Calendar.InferTradingDays。
+func OpenCalendar(ctx context.Context, root string, workers int) (*calendar.Calendar, error) {
+	cal, err := calendar.Open(root)
+	if err != nil {
+		return nil, err
+	}
+	dates, err := UnionDates(ctx, root, workers)
+	if err != nil {
+		return nil, err
+	}
+	cal.InferTradingDays(dates)
+	return cal, nil
+}
+
+// scan 读取一个数据文件, 同时计算校验和
+func scan(ctx context.Context, root, name string, opts *readr.Options) Entry {
+	e := Entry{File: name}
+	path := filepath.Join(root, name)
+	f, err := os.Open(path)
+	if err != nil {
+		e.Err = err.Error()
+		return e
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil {
+		e.Size, e.ModTime = fi.Size(), fi.ModTime()
+	}
+	h := sha256.New()
+	r := io.TeeReader(f, h)
+	s, err := readr.NewScanner(ctx, r, path, opts)
+	if err == nil {
+		e.Format = s.Format()
+		for s.Scan() {
+			bar := s.Bar()
+			if e.Rows == 0 {
+				e.First = bar.Time
+			}
+			e.Last, e.LastPower = bar.Time, bar.Power
+			e.Rows++
+		}
+		err = s.Err()
+	}
+	if err != nil {
+		e.Err = err.Error()
+	}
+	if _, err := io.Copy(h, r); err != nil && e.Err == "" {
+		e.Err = err.Error()
+	}
+	e.Checksum = hex.EncodeToString(h.Sum(nil))
+	return e
+}
+
+func (c *Catalog) sort() {
+	sort.Slice(c.Entries, func(i, j int) bool {
+		a, b := &c.Entries[i], &c.Entries[j]
+		if a.Code != b.Code {
+			return a.Code.String() < b.Code.String()
+		}
+		return a.File < b.File
+	})
+}
+
+// Lookup 返回股票code(可以是任何写法)的记录, 没有时返回nil。
+// 同一股票有多个文件时优先返回csv文件。
+func (c *Catalog) Lookup(code string) *Entry {
+	sc, err := stockcode.Parse(code)
+	if err != nil {
+		return nil
+	}
+	var found *Entry
+	for i := range c.Entries {
+		e := &c.Entries[i]
+		if e.Code == sc && (found == nil || e.Format == readr.FormatCSV) {
+			found = e
+		}
+	}
+	return found
+}
+
+// Latest 返回全部文件中最新的一天
+func (c *Catalog) Latest() time.Time {
+	var t time.Time
+	for i := range c.Entries {
+		if c.Entries[i].Last.After(t) {
+			t = c.Entries[i].Last
+		}
+	}
+	return t
+}
+
+// Stale 报告e是否过时, 即最后一天比Latest早MaxLag天以上, 如长期停牌或没有更新的股票
+func (c *Catalog) Stale(e *Entry) bool {
+	lag := c.MaxLag
+	if lag <= 0 {
+		lag = DefaultMaxLag
+	}
+	return !e.Empty() && e.Last.Before(c.Latest().AddDate(0, 0, -lag))
+}
+
+// Changed 报告e记录之后文件是否被修改过, 此时清单中的信息不可信
+func (c *Catalog) Changed(e *Entry) bool {
+	fi, err := os.Stat(filepath.Join(c.Root, e.File))
+	if err != nil {
+		return true
+	}
+	return fi.Size() != e.Size || !fi.ModTime().Equal(e.ModTime)
+}
+
+// Covering 返回数据覆盖了from至to的记录
+func (c *Catalog) Covering(from, to time.Time) []Entry {
+	var res []Entry
+	for i := range c.Entries {
+		e := &c.Entries[i]
+		if !e.Empty() && !e.First.After(from) && !e.Last.Before(to) {
+			res = append(res, *e)
+		}
+	}
+	return res
+}
+
+// Usable 从codes中去掉清单表明为空或过时的股票, 返回其余的代码及被去掉的代码和原因。
+// 清单中没有的, 或记录后文件被修改过的股票不能判断, 予以保留。
+func (c *Catalog) Usable(codes []stockcode.Code) (usable []stockcode.Code, skipped map[stockcode.Code]string) {
+	skipped = make(map[stockcode.Code]string)
+	for _, code := range codes {
+		e := c.Lookup(code.String())
+		switch {
+		case e == nil || c.Changed(e):
+			usable = append(usable, code)
+		case e.Err != "":
+			skipped[code] = e.Err
+		case e.Rows == 0:
+			skipped[code] = "empty"
+		case c.Stale(e):
+			skipped[code] = "stale since " + e.Last.Format(readr.DateLayout)
+		default:
+			usable = append(usable, code)
+		}
+	}
+	return usable, skipped
+}
+
+// Filter 按数据目录root中的清单筛选codes, 没有清单时原样返回
+func Filter(root string, codes []stockcode.Code) ([]stockcode.Code, map[stockcode.Code]string, error) {
+	c, err := Load(root)
+	if os.IsNotExist(err) {
+		return codes, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	usable, skipped := c.Usable(codes)
+	return usable, skipped, nil
+}
+
+// Load 读取数据目录root中的清单。没有清单时返回的错误满足os.IsNotExist。
+func Load(root string) (*Catalog, error) {
+	f, err := os.Open(ManifestPath(root))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c := &Catalog{Root: root}
+	if err := c.read(f); err != nil {
+		return nil, fmt.Errorf("%s: %v", ManifestPath(root), err)
+	}
+	return c, nil
+}
+
+func (c *Catalog) read(r io.Reader) error {
+	rd := csv.NewReader(r)
+	rd.FieldsPerRecord = -1
+	header, err := rd.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(header) == 0 || !strings.HasPrefix(header[0], "#v") {
+		return fmt.Errorf("missing header")
+	}
+	if v, err := strconv.Atoi(header[0][2:]); err != nil || v > Version {
+		return fmt.Errorf("unsupported version %q", header[0])
+	}
+	index := make(map[string]int)
+	for i, name := range header[1:] {
+		index[name] = i
+	}
+	for {
+		record, err := rd.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := rd.FieldPos(0)
+		get := func(name string) string {
+			if i, ok := index[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		e, err := parseEntry(get)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		c.Entries = append(c.Entries, e)
+	}
+}
+
+func parseEntry(get func(name string) string) (e Entry, err error) {
+	if e.Code, err = stockcode.Parse(get("code")); err != nil {
+		return e, err
+	}
+	e.File, e.Checksum, e.Err = get("file"), get("sha256"), get("error")
+	e.Format = parseFormat(get("format"))
+	if e.First, err = parseDate(get("first")); err != nil {
+		return e, err
+	}
+	if e.Last, err = parseDate(get("last")); err != nil {
+		return e, err
+	}
+	if e.Rows, err = strconv.Atoi(get("rows")); err != nil {
+		return e, fmt.Errorf("rows: %v", err)
+	}
+	if s := get("power"); s != "" {
+		if e.LastPower, err = strconv.ParseFloat(s, 64); err != nil {
+			return e, fmt.Errorf("power: %v", err)
+		}
+	}
+	if e.Size, err = strconv.ParseInt(get("size"), 10, 64); err != nil {
+		return e, fmt.Errorf("size: %v", err)
+	}
+	if e.ModTime, err = time.Parse(time.RFC3339Nano, get("mtime")); err != nil {
+		return e, fmt.Errorf("mtime: %v", err)
+	}
+	return e, nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return readr.ParseDate(s)
+}
+
+func parseFormat(s string) readr.Format {
+	for _, f := range []readr.Format{readr.FormatCSV, readr.FormatTable, readr.FormatIfeng} {
+		if f.String() == s {
+			return f
+		}
+	}
+	return readr.FormatAuto
+}
+
+// Save 把清单写入数据目录中的ManifestName
+func (c *Catalog) Save() error {
+	return safefile.WriteFile(ManifestPath(c.Root), c.Write)
+}
+
+// Header 是Records各列的名字
+func Header() []string {
+	return append([]string(nil), columns...)
+}
+
+// Records 返回各记录的文本, 列同Header
+func (c *Catalog) Records() [][]string {
+	records := make([][]string, len(c.Entries))
+	for i := range c.Entries {
+		e := &c.Entries[i]
+		power := ""
+		if e.LastPower != 0 {
+			power = strconv.FormatFloat(e.LastPower, 'f', -1, 64)
+		}
+		records[i] = []string{
+			e.Code.String(), e.File, e.Format.String(),
+			formatDate(e.First), formatDate(e.Last),
+			strconv.Itoa(e.Rows), power, e.Checksum,
+			strconv.FormatInt(e.Size, 10), e.ModTime.Format(time.RFC3339Nano), e.Err,
+		}
+	}
+	return records
+}
+
+// Write 以清单格式写出c
+func (c *Catalog) Write(w io.Writer) error {
+	wr := csv.NewWriter(w)
+	wr.Write(append([]string{fmt.Sprintf("#v%d", Version)}, columns...))
+	wr.WriteAll(c.Records())
+	return wr.Error()
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(readr.DateLayout)
+}
@@ -0,0 +1,88 @@
+package catalog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"stockstat/readr"
+	"testing"
+)
+
+func TestBuildFormats(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		// 凤凰网的JSON, 没有扩展名, 如stat/600000
+		"600000": `{"record":[` +
+			`["2014-01-28","9.120","9.290","9.190","9.120","573336.19","0.070","0.77","9.190","9.190","9.190","573,336.19","573,336.19","573,336.19","0.38"],` +
+			`["2014-01-29","9.240","9.310","9.290","9.200","710428.25","0.100","1.09","9.240","9.240","9.240","641,882.22","641,882.22","641,882.22","0.48"]]}`,
+		// csv2table转换前的表格文件, 没有表头
+		"000001": "2014-01-28 9.12 9.29 9.19 9.12 573336 5270000 1.0\n" +
+			"2014-01-29 9.24 9.31 9.29 9.20 710428 6590000 1.0\n" +
+			"2014-01-30 9.25 9.30 9.22 9.18 610000 5620000 1.0\n",
+		"600036.csv": "#stockstat v1 adjust=none\r\n#date,open,high,cloe,low,volumn,pow\r\n" +
+			"2014-01-28,9.120,9.290,9.190,9.120,573336,1.000\r\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := Build(context.Background(), root, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]struct {
+		format readr.Format
+		rows   int
+	}{
+		"600000":     {readr.FormatIfeng, 2},
+		"000001":     {readr.FormatTable, 3},
+		"600036.csv": {readr.FormatCSV, 1},
+	}
+	if len(c.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(c.Entries), len(want))
+	}
+	for _, e := range c.Entries {
+		w := want[e.File]
+		if e.Err != "" || e.Format != w.format || e.Rows != w.rows {
+			t.Errorf("%s: format %v, rows %d, err %q; want %v, %d", e.File, e.Format, e.Rows, e.Err, w.format, w.rows)
+		}
+	}
+}
+
+func TestBuildSkipsNonStock(t *testing.T) {
+	root := t.TempDir()
+	csv := "#stockstat v1 adjust=none\r\n#date,open,high,close,low,volume,pow\r\n" +
+		"2014-01-28,9.120,9.290,9.190,9.120,573336,1.000\r\n"
+	files := map[string]string{
+		"600000.csv":    csv,
+		"6.txt":         `{"record":[["2014-01-29","9.240","9.310","9.290","9.200","710428.25"]]}`, // 凤凰网样例, 不是000006
+		"1.csv":         csv,
+		"sh600036.csv":  csv,
+		"6000000.csv":   csv,
+		"stocklist.csv": "600000,浦发银行\r\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	c, err := Build(ctx, root, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Entries) != 1 || c.Entries[0].File != "600000.csv" || c.Entries[0].Code.String() != "600000.SH" {
+		t.Errorf("entries %+v, want only 600000.csv", c.Entries)
+	}
+	if c.Lookup("000006") != nil || c.Lookup("000001") != nil {
+		t.Error("short names catalogued as zero-padded codes")
+	}
+	dates, err := UnionDates(ctx, root, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dates) != 1 || dates[0].Format(readr.DateLayout) != "2014-01-28" {
+		t.Errorf("UnionDates = %v, want only the dates of 600000.csv", dates)
+	}
+}
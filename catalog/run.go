@@ -0,0 +1,64 @@
+package catalog
+
+import (
+	"context"
+	"stockstat/calendar"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/readr"
+	"time"
+)
+
+// Run 扫描目录root, 把清单保存到root中并写到out
+func Run(c *config.Config, out *cli.Output, root string) error {
+	cat, err := Build(context.Background(), root, c.Workers)
+	if err != nil {
+		return err
+	}
+	if err := cat.Save(); err != nil {
+		return err
+	}
+	return out.WriteTable(Header(), cat.Records())
+}
+
+// RunCalendar 把args给出的日期范围[from [to]]内的交易日写到out, 缺省为from所在年份(缺省为今年)的全年。
+// infer为true时以数据目录中各数据文件的日期为其覆盖范围内的交易日;
+// holidays为true时改为写出休市的工作日。
+func RunCalendar(c *config.Config, out *cli.Output, args []string, infer, holidays bool) error {
+	now := time.Now()
+	from := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	var err error
+	if len(args) > 0 {
+		if from, err = readr.ParseDate(args[0]); err != nil {
+			return cli.Usagef("bad from date %q", args[0])
+		}
+	}
+	to := time.Date(from.Year(), time.December, 31, 0, 0, 0, 0, time.UTC)
+	if len(args) > 1 {
+		if to, err = readr.ParseDate(args[1]); err != nil {
+			return cli.Usagef("bad to date %q", args[1])
+		}
+	}
+
+	cal, err := calendar.Open(c.DataDir)
+	if err != nil {
+		return err
+	}
+	if infer {
+		dates, err := UnionDates(context.Background(), c.DataDir, c.Workers)
+		if err != nil {
+			return err
+		}
+		cal.AddTradingDays(dates)
+	}
+
+	days := cal.TradingDays(from, to)
+	if holidays {
+		days = cal.Holidays(from, to)
+	}
+	rows := make([][]string, len(days))
+	for i, t := range days {
+		rows[i] = []string{t.Format(readr.DateLayout), t.Weekday().String()[:3]}
+	}
+	return out.WriteTable([]string{"date", "weekday"}, rows)
+}
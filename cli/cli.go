@@ -0,0 +1,197 @@
+// package cli 实现stockstat的子命令框架: 每个子命令有自己的参数及--help,
+// 共用config的设置参数及--format, --out输出参数, 出错时以非0值退出。
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"stockstat/config"
+	"strings"
+)
+
+// 退出码
+const (
+	ExitOK    = 0
+	ExitError = 1 // 子命令运行出错
+	ExitUsage = 2 // 命令行参数错误
+)
+
+// Command 是一个子命令
+type Command struct {
+	Name  string
+	Args  string // 用法中参数部分的说明, 如"code"或"[dir]"
+	Short string // 一行说明
+	Long  string // 详细说明, 可以为空
+	// NArgs 是位置参数个数的范围, Max<0表示不限
+	MinArgs, MaxArgs int
+	// Output 为true时登记--format及--out参数, 结果经Env.Out写出
+	Output bool
+	// DefaultOut 是未给出--out时的输出文件(相对于配置中的输出目录), 为空表示标准输出
+	DefaultOut string
+	// Flags 登记子命令自己的参数, 可以为nil
+	Flags func(fs *flag.FlagSet)
+	Run   func(env *Env) error
+}
+
+// Env 是子命令运行时的环境
+type Env struct {
+	Config *config.Config
+	Out    *Output // 未设置Command.Output时为nil
+	Args   []string
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// UsageError 表示命令行参数错误, 返回它的子命令以ExitUsage退出
+type UsageError struct {
+	Msg string
+}
+
+func (e *UsageError) Error() string { return e.Msg }
+
+// Usagef 返回格式化的*UsageError
+func Usagef(format string, args ...interface{}) error {
+	return &UsageError{fmt.Sprintf(format, args...)}
+}
+
+// App 是由若干子命令组成的程序
+type App struct {
+	Name     string
+	Commands []*Command
+	Stdout   io.Writer
+	Stderr   io.Writer
+}
+
+// Lookup 按名字查找子命令, 找不到时返回nil
+func (app *App) Lookup(name string) *Command {
+	for _, c := range app.Commands {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Main 运行args(不含程序名)指定的子命令并返回退出码。
+// "help <cmd>"及"<cmd> --help"打印子命令的用法。
+func (app *App) Main(args []string) int {
+	if app.Stdout == nil {
+		app.Stdout = os.Stdout
+	}
+	if app.Stderr == nil {
+		app.Stderr = os.Stderr
+	}
+	if len(args) == 0 {
+		app.usage(app.Stderr)
+		return ExitUsage
+	}
+	name, args := args[0], args[1:]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) == 0 {
+			app.usage(app.Stdout)
+			return ExitOK
+		}
+		c := app.Lookup(args[0])
+		if c == nil {
+			fmt.Fprintf(app.Stderr, "%s: unknown command %q\n", app.Name, args[0])
+			return ExitUsage
+		}
+		return app.run(c, []string{"-help"}, app.Stdout)
+	}
+	c := app.Lookup(name)
+	if c == nil {
+		fmt.Fprintf(app.Stderr, "%s: unknown command %q\n", app.Name, name)
+		app.usage(app.Stderr)
+		return ExitUsage
+	}
+	return app.run(c, args, app.Stderr)
+}
+
+// run 解析args并运行子命令c, 用法打印到usage
+func (app *App) run(c *Command, args []string, usage io.Writer) int {
+	fs, out := app.flagSet(c, usage)
+	printed := false // flag包解析出错时已打印错误及用法
+	printUsage := fs.Usage
+	fs.Usage = func() { printed = true; printUsage() }
+	cfg, err := config.Parse(fs, args)
+	fs.Usage = printUsage
+	if err == flag.ErrHelp {
+		return ExitOK
+	}
+	if err != nil { // 参数, 配置文件或环境变量有误
+		if !printed {
+			fmt.Fprintf(app.Stderr, "%s %s: %v\n", app.Name, c.Name, err)
+		}
+		return ExitUsage
+	}
+	n := fs.NArg()
+	if n < c.MinArgs || (c.MaxArgs >= 0 && n > c.MaxArgs) {
+		fmt.Fprintf(app.Stderr, "%s %s: wrong number of arguments\n", app.Name, c.Name)
+		fs.Usage()
+		return ExitUsage
+	}
+	env := &Env{Config: cfg, Args: fs.Args(), Stdout: app.Stdout, Stderr: app.Stderr}
+	if out != nil {
+		if err := out.check(); err != nil {
+			fmt.Fprintf(app.Stderr, "%s %s: %v\n", app.Name, c.Name, err)
+			return ExitUsage
+		}
+		if out.Path == "" && c.DefaultOut != "" {
+			out.Path = cfg.OutPath(c.DefaultOut)
+		}
+		out.stdout = app.Stdout
+		env.Out = out
+	}
+	err = c.Run(env)
+	if err == nil {
+		return ExitOK
+	}
+	fmt.Fprintf(app.Stderr, "%s %s: %v\n", app.Name, c.Name, err)
+	var ue *UsageError
+	if errors.As(err, &ue) {
+		fs.Usage()
+		return ExitUsage
+	}
+	return ExitError
+}
+
+// flagSet 建立子命令c的参数集, 用法打印到w
+func (app *App) flagSet(c *Command, w io.Writer) (*flag.FlagSet, *Output) {
+	fs := flag.NewFlagSet(app.Name+" "+c.Name, flag.ContinueOnError)
+	fs.SetOutput(w)
+	var out *Output
+	if c.Output {
+		out = &Output{}
+		out.register(fs, c.DefaultOut)
+	}
+	if c.Flags != nil {
+		c.Flags(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(w, "usage: %s %s [flags] %s\n", app.Name, c.Name, c.Args)
+		if c.Short != "" {
+			fmt.Fprintf(w, "\n%s\n", c.Short)
+		}
+		if c.Long != "" {
+			fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(c.Long))
+		}
+		fmt.Fprintf(w, "\nflags:\n")
+		fs.PrintDefaults()
+	}
+	return fs, out
+}
+
+func (app *App) usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s <command> [flags] [args]\n\ncommands:\n", app.Name)
+	cmds := append([]*Command(nil), app.Commands...)
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	for _, c := range cmds {
+		fmt.Fprintf(w, "  %-10s %s\n", c.Name, c.Short)
+	}
+	fmt.Fprintf(w, "\nRun '%s help <command>' for details.\n", app.Name)
+}
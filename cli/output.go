@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"stockstat/readr"
+	"strings"
+	"text/tabwriter"
+)
+
+// 输出格式
+const (
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+	FormatTable = "table"
+)
+
+// Output 是子命令结果的去向, 由--format及--out参数决定
+type Output struct {
+	Format string // csv, json或table
+	Path   string // 输出文件, 为空或"-"时写到标准输出
+	stdout io.Writer
+}
+
+func (o *Output) register(fs *flag.FlagSet, def string) {
+	fs.StringVar(&o.Format, "format", FormatCSV, "output format: csv, json or table")
+	usage := "output file, - for stdout"
+	if def != "" {
+		usage += " (default " + def + " in output directory)"
+	} else {
+		usage += " (default stdout)"
+	}
+	fs.StringVar(&o.Path, "out", "", usage)
+}
+
+func (o *Output) check() error {
+	switch o.Format {
+	case FormatCSV, FormatJSON, FormatTable:
+		return nil
+	}
+	return fmt.Errorf("unknown format %q, want csv, json or table", o.Format)
+}
+
+// TableWriter 逐行写出一张表, 写完后须调用Close
+type TableWriter interface {
+	Write(row []string) error
+	Close() error
+}
+
+// open 打开输出, 写到标准输出时closer为nil
+func (o *Output) open() (w io.Writer, closer io.Closer, err error) {
+	if o.Path != "" && o.Path != "-" {
+		f, err := os.Create(o.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return f, f, nil
+	}
+	if o.stdout != nil {
+		return o.stdout, nil, nil
+	}
+	return os.Stdout, nil, nil
+}
+
+// WriteFrame 按o的格式写出frame, 分别使用readr的WriteCSV, WriteJSON及WriteTable
+func (o *Output) WriteFrame(frame *readr.Frame, opts *readr.WriteOptions) error {
+	w, closer, err := o.open()
+	if err != nil {
+		return err
+	}
+	switch o.Format {
+	case FormatJSON:
+		err = frame.WriteJSON(w, opts)
+	case FormatTable:
+		err = frame.WriteTable(w, opts)
+	default:
+		err = frame.WriteCSV(w, opts)
+	}
+	if closer != nil {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// NewTable 按o的格式写出表头为header的表
+func (o *Output) NewTable(header []string) (TableWriter, error) {
+	w, closer, err := o.open()
+	if err != nil {
+		return nil, err
+	}
+	var t TableWriter
+	switch o.Format {
+	case FormatJSON:
+		t = newJSONTable(w, header)
+	case FormatTable:
+		t = newTextTable(w, header)
+	default:
+		t = newCSVTable(w, header)
+	}
+	if closer != nil {
+		t = &fileTable{t, closer}
+	}
+	return t, nil
+}
+
+// WriteTable 写出一张完整的表
+func (o *Output) WriteTable(header []string, rows [][]string) error {
+	t, err := o.NewTable(header)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := t.Write(row); err != nil {
+			t.Close()
+			return err
+		}
+	}
+	return t.Close()
+}
+
+type fileTable struct {
+	TableWriter
+	file io.Closer
+}
+
+func (t *fileTable) Close() error {
+	err := t.TableWriter.Close()
+	if cerr := t.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type csvTable struct {
+	w *csv.Writer
+}
+
+func newCSVTable(w io.Writer, header []string) *csvTable {
+	t := &csvTable{csv.NewWriter(w)}
+	t.w.Write(header)
+	return t
+}
+
+func (t *csvTable) Write(row []string) error {
+	return t.w.Write(row)
+}
+
+func (t *csvTable) Close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// textTable 以空白对齐各列, 须读完全部行才能确定列宽
+type textTable struct {
+	w *tabwriter.Writer
+}
+
+func newTextTable(w io.Writer, header []string) *textTable {
+	t := &textTable{tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)}
+	t.Write(header)
+	return t
+}
+
+func (t *textTable) Write(row []string) error {
+	_, err := fmt.Fprintln(t.w, strings.Join(row, "\t"))
+	return err
+}
+
+func (t *textTable) Close() error {
+	return t.w.Flush()
+}
+
+// jsonTable 写出对象数组, 每行一个对象, 键为表头
+type jsonTable struct {
+	w      *bufio.Writer
+	header []string
+	n      int
+}
+
+func newJSONTable(w io.Writer, header []string) *jsonTable {
+	return &jsonTable{w: bufio.NewWriter(w), header: header}
+}
+
+func (t *jsonTable) Write(row []string) error {
+	sep := ",\n"
+	if t.n == 0 {
+		sep = "[\n"
+	}
+	t.n++
+	t.w.WriteString(sep + "{")
+	for i, v := range row {
+		if i > 0 {
+			t.w.WriteByte(',')
+		}
+		key := fmt.Sprintf("col%d", i+1)
+		if i < len(t.header) {
+			key = t.header[i]
+		}
+		k, _ := json.Marshal(key)
+		x, _ := json.Marshal(v)
+		t.w.Write(k)
+		t.w.WriteByte(':')
+		t.w.Write(x)
+	}
+	_, err := t.w.WriteString("}")
+	return err
+}
+
+func (t *jsonTable) Close() error {
+	if t.n == 0 {
+		t.w.WriteString("[")
+	}
+	t.w.WriteString("\n]\n")
+	return t.w.Flush()
+}
@@ -0,0 +1,270 @@
+// package config 为各命令提供数据目录, 股票列表文件, 输出目录及并发数等设置。
+//
+// 设置依次来自: 缺省值, 配置文件, 环境变量, 命令行参数, 后者覆盖前者。
+// 配置文件由-config参数或环境变量STOCKSTAT_CONFIG指定, 未指定时依次查找
+// ./stockstat.conf 及 $HOME/.stockstat.conf。文件每行一项"key = value", '#'开始注释:
+//
+//	# stockstat.conf
+//	data     = /home/jns/diskD/stockdata
+//	roster   = stocklist.csv
+//	out      = .
+//	workers  = 5
+//	adjust   = forward
+//	provider = sina
+//	source   = http://127.0.0.1:8080/sina/%s.phtml
+package config
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"strconv"
+	"strings"
+)
+
+// Config 是各命令共用的设置
+type Config struct {
+	DataDir  string           // 数据目录, 存放<code>.csv及股票列表文件
+	Roster   string           // 股票列表文件名, 相对路径相对于DataDir
+	OutDir   string           // 结果输出目录
+	Workers  int              // 同时处理的股票数
+	Adjust   readr.Adjustment // 读取数据时价格的复权方式
+	Provider string           // 下载日K线的行情源: ifeng或sina, 见package update
+	Source   string           // 行情源的地址, 为空时使用行情源的缺省地址
+}
+
+// 缺省设置
+const (
+	DefaultDataDir  = "./stockdata/"
+	DefaultRoster   = "stocklist.csv"
+	DefaultOutDir   = "."
+	DefaultWorkers  = 5
+	DefaultProvider = "ifeng"
+)
+
+// 环境变量名
+const (
+	EnvConfig   = "STOCKSTAT_CONFIG"
+	EnvDataDir  = "STOCKSTAT_DATA"
+	EnvRoster   = "STOCKSTAT_ROSTER"
+	EnvOutDir   = "STOCKSTAT_OUT"
+	EnvWorkers  = "STOCKSTAT_WORKERS"
+	EnvAdjust   = "STOCKSTAT_ADJUST"
+	EnvProvider = "STOCKSTAT_PROVIDER"
+	EnvSource   = "STOCKSTAT_SOURCE"
+)
+
+// Default 返回缺省设置
+func Default() *Config {
+	return &Config{
+		DataDir:  DefaultDataDir,
+		Roster:   DefaultRoster,
+		OutDir:   DefaultOutDir,
+		Workers:  DefaultWorkers,
+		Provider: DefaultProvider,
+	}
+}
+
+// RosterPath 返回股票列表文件的路径
+func (c *Config) RosterPath() string {
+	if filepath.IsAbs(c.Roster) {
+		return c.Roster
+	}
+	return filepath.Join(c.DataDir, c.Roster)
+}
+
+// DataPath 返回股票code的数据文件路径。code可以是package stockcode认得的任何写法,
+// 数据文件以6位数字命名。
+func (c *Config) DataPath(code string) string {
+	if sc, err := stockcode.Parse(code); err == nil {
+		code = sc.Number
+	}
+	return filepath.Join(c.DataDir, code+".csv")
+}
+
+// OutPath 返回输出文件name的路径
+func (c *Config) OutPath(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(c.OutDir, name)
+}
+
+// Limiter 返回容量为Workers的信号量, 用于限制并发数
+func (c *Config) Limiter() chan struct{} {
+	n := c.Workers
+	if n <= 0 {
+		n = 1
+	}
+	return make(chan struct{}, n)
+}
+
+// flags 保存命令行参数, 只有用户给出的参数才覆盖其它来源
+type flags struct {
+	config   string
+	dataDir  string
+	roster   string
+	outDir   string
+	workers  int
+	adjust   string
+	provider string
+	source   string
+}
+
+func (f *flags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.config, "config", "", "config file (env "+EnvConfig+")")
+	fs.StringVar(&f.dataDir, "data", DefaultDataDir, "data directory (env "+EnvDataDir+")")
+	fs.StringVar(&f.roster, "roster", DefaultRoster, "stock roster file, relative to data directory (env "+EnvRoster+")")
+	fs.StringVar(&f.outDir, "outdir", DefaultOutDir, "output directory (env "+EnvOutDir+")")
+	fs.IntVar(&f.workers, "workers", DefaultWorkers, "number of stocks processed concurrently (env "+EnvWorkers+")")
+	fs.StringVar(&f.adjust, "adjust", "none", "price adjustment: none, forward or backward (env "+EnvAdjust+")")
+	fs.StringVar(&f.provider, "provider", DefaultProvider, "where data is downloaded from: ifeng or sina (env "+EnvProvider+")")
+	fs.StringVar(&f.source, "source", "", "URL of the provider, empty for its default (env "+EnvSource+")")
+}
+
+// Parse 在fs上登记-config, -data, -roster, -outdir, -workers, -adjust, -provider, -source参数, 解析args(不含程序名),
+// 并返回合并了配置文件及环境变量后的设置。其余参数可由fs.Args()取得。
+func Parse(fs *flag.FlagSet, args []string) (*Config, error) {
+	var f flags
+	f.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
+	c := Default()
+	path := f.config
+	if path == "" {
+		path = os.Getenv(EnvConfig)
+	}
+	if path == "" {
+		path = findConfig()
+	}
+	if path != "" {
+		if err := c.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.LoadEnv(); err != nil {
+		return nil, err
+	}
+	if set["data"] {
+		c.DataDir = f.dataDir
+	}
+	if set["roster"] {
+		c.Roster = f.roster
+	}
+	if set["outdir"] {
+		c.OutDir = f.outDir
+	}
+	if set["workers"] {
+		if err := c.set("workers", strconv.Itoa(f.workers)); err != nil {
+			return nil, err
+		}
+	}
+	if set["adjust"] {
+		if err := c.set("adjust", f.adjust); err != nil {
+			return nil, err
+		}
+	}
+	if set["provider"] {
+		c.Provider = f.provider
+	}
+	if set["source"] {
+		c.Source = f.source
+	}
+	return c, nil
+}
+
+// findConfig 返回缺省位置上存在的配置文件, 没有时返回""
+func findConfig() string {
+	candidates := []string{"stockstat.conf"}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".stockstat.conf"))
+	}
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// LoadFile 读取配置文件path中的设置
+func (c *Config) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		i := strings.IndexByte(text, '=')
+		if i < 0 {
+			return fmt.Errorf("%s:%d: want key = value", path, line)
+		}
+		key, value := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		if err := c.set(key, value); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// LoadEnv 读取环境变量中的设置
+func (c *Config) LoadEnv() error {
+	for key, env := range map[string]string{
+		"data": EnvDataDir, "roster": EnvRoster, "out": EnvOutDir, "workers": EnvWorkers, "adjust": EnvAdjust,
+		"provider": EnvProvider, "source": EnvSource,
+	} {
+		if value, ok := os.LookupEnv(env); ok && value != "" {
+			if err := c.set(key, value); err != nil {
+				return fmt.Errorf("%s: %v", env, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) set(key, value string) error {
+	switch key {
+	case "data":
+		c.DataDir = value
+	case "roster":
+		c.Roster = value
+	case "out":
+		c.OutDir = value
+	case "workers":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("workers: bad value %q", value)
+		}
+		c.Workers = n
+	case "adjust":
+		a, err := readr.ParseAdjustment(value)
+		if err != nil {
+			return err
+		}
+		c.Adjust = a
+	case "provider":
+		c.Provider = value
+	case "source":
+		c.Source = value
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"stockstat/readr"
+	"strings"
+	"testing"
+)
+
+// clearEnv 清除影响Parse的环境变量, 并使缺省位置上没有配置文件
+func clearEnv(t *testing.T) {
+	for _, env := range []string{EnvConfig, EnvDataDir, EnvRoster, EnvOutDir, EnvWorkers, EnvAdjust, EnvProvider, EnvSource} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("HOME", t.TempDir())
+}
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stockstat.conf")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func parse(args ...string) (*Config, error) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return Parse(fs, args)
+}
+
+func TestParsePrecedence(t *testing.T) {
+	clearEnv(t)
+	conf := writeConfig(t, "# test\ndata = /file/data\nworkers = 3 # comment\nadjust = backward\nroster = file.csv\n")
+
+	c, err := parse("-config", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DataDir != "/file/data" || c.Workers != 3 || c.Adjust != readr.AdjustBackward || c.Roster != "file.csv" || c.OutDir != DefaultOutDir {
+		t.Errorf("file: %+v", c)
+	}
+
+	// 环境变量覆盖配置文件, 配置文件也可以由环境变量指定
+	t.Setenv(EnvConfig, conf)
+	t.Setenv(EnvDataDir, "/env/data")
+	t.Setenv(EnvWorkers, "7")
+	c, err = parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DataDir != "/env/data" || c.Workers != 7 || c.Adjust != readr.AdjustBackward || c.Roster != "file.csv" {
+		t.Errorf("env: %+v", c)
+	}
+
+	// 明确给出的参数覆盖环境变量; 没有给出的参数不覆盖, 即使与缺省值不同
+	c, err = parse("-data", "/flag/data", "-adjust", "forward", "rest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DataDir != "/flag/data" || c.Adjust != readr.AdjustForward || c.Workers != 7 || c.Roster != "file.csv" {
+		t.Errorf("flags: %+v", c)
+	}
+
+	// 给出与缺省值相同的参数仍然覆盖
+	c, err = parse("-workers", "5", "-data", DefaultDataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Workers != DefaultWorkers || c.DataDir != DefaultDataDir {
+		t.Errorf("default-valued flags: %+v", c)
+	}
+}
+
+func TestParseDefaultFile(t *testing.T) {
+	clearEnv(t)
+	home := os.Getenv("HOME")
+	os.WriteFile(filepath.Join(home, ".stockstat.conf"), []byte("out = /home/out\n"), 0644)
+	c, err := parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.OutDir != "/home/out" {
+		t.Errorf("OutDir = %q, want the value from $HOME/.stockstat.conf", c.OutDir)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	clearEnv(t)
+	tests := []struct {
+		conf string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{conf: "data = x\nworkers = many\n", want: ":2: workers: bad value \"many\""},
+		{conf: "\n\nworkers = 0\n", want: ":3: workers: bad value \"0\""},
+		{conf: "adjust = sideways\n", want: ":1: "},
+		{conf: "data\n", want: ":1: want key = value"},
+		{conf: "colour = red\n", want: ":1: unknown key \"colour\""},
+		{env: map[string]string{EnvWorkers: "-2"}, want: EnvWorkers + ": workers: bad value"},
+		{env: map[string]string{EnvAdjust: "up"}, want: EnvAdjust + ": "},
+		{args: []string{"-workers", "0"}, want: "workers: bad value \"0\""},
+		{args: []string{"-adjust", "up"}, want: ""},
+	}
+	for _, tt := range tests {
+		args := tt.args
+		prefix := ""
+		if tt.conf != "" {
+			prefix = writeConfig(t, tt.conf)
+			args = append([]string{"-config", prefix}, args...)
+		}
+		for k, v := range tt.env {
+			t.Setenv(k, v)
+		}
+		_, err := parse(args...)
+		if err == nil || !strings.HasPrefix(err.Error(), prefix+tt.want) {
+			t.Errorf("conf %q env %v args %v: err = %v, want prefix %q", tt.conf, tt.env, tt.args, err, prefix+tt.want)
+		}
+		for k := range tt.env {
+			t.Setenv(k, "")
+		}
+	}
+}
@@ -1,20 +1,21 @@
-// corr 根据股票列表文件 stocklist.csv 与 各只股票的价格文件
+// package corr 根据股票列表文件 stocklist.csv 与 各只股票的价格文件
 // 计算两两的相关性。
 // 程序输出corr.csv文件，将股票相关系数矩阵以csv格式输出。
 //			,stock1, stock2, ...
 // stock1	,xi_11,  xi_12, ...
 // stock2	,xi_21,  xi_22, ...
-
-package main
+package corr
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
 	"log"
 	"math"
 	//	"net/http"
 	"os"
-	"path"
+	"stockstat/calendar"
+	"stockstat/readr"
 	"time"
 )
 
@@ -101,31 +102,25 @@ func (c *CorrelationMatrix) ComputeFor(I, J int) {
 
 	// 数据清洗，将数据日期一一对应，删除无法比对的数据
 	dates, closesI, closesJ := LoadAndCleanData(c.StockCodes[I], c.StockCodes[J])
-	if dates == nil || len(dates) < 5 {
+	if len(dates) < 3 {
 		return
 	}
 
+	times := make([]time.Time, len(dates))
+	for i, date := range dates {
+		t, err := readr.ParseDate(date)
+		if err != nil {
+			return
+		}
+		times[i] = t
+	}
+
 	// 以一周为一个计算单位，计算相关性的频率
 	counts := 0
 	freqs := 0
-	for {
-		// 如果一周天数不足5天，为无效周，快进到下一周
-		i_monday := NextMondayIndex(dates)
-		if i_monday < 0 {
-			break
-		}
-		dates = dates[i_monday:]
-		closesI = closesI[i_monday:]
-		closesJ = closesJ[i_monday:]
-		i_monday = 0
-		j_friday := NextFridayIndex(dates)
-		if j_friday < 0 {
-			break
-		}
-		if (j_friday - i_monday) != 4 { // 不在同一周
-			dates = dates[j_friday:]
-			closesI = closesI[j_friday:]
-			closesJ = closesJ[j_friday:]
+	for _, week := range cal.Group(times, calendar.Week) {
+		// 缺少交易日的为无效周，跳过
+		if !week.Full || week.Len() < 3 {
 			continue
 		}
 
@@ -133,53 +128,51 @@ func (c *CorrelationMatrix) ComputeFor(I, J int) {
 		counts += 1
 
 		// 一周股价
-		cI := closesI[i_monday : j_friday+1]
-		cJ := closesJ[i_monday : j_friday+1]
-		dates = dates[j_friday:]
-		closesI = closesI[j_friday:]
-		closesJ = closesJ[j_friday:]
+		cI := closesI[week.Lo:week.Hi]
+		cJ := closesJ[week.Lo:week.Hi]
+		n := len(cI)
 
 		// 标准化cI, cJ
 		sumI, sumJ := 0.0, 0.0
-		for i := 0; i < 5; i++ {
+		for i := 0; i < n; i++ {
 			sumI += cI[i] * cI[i]
 			sumJ += cJ[i] * cJ[i]
 		}
 		sumI, sumJ = math.Sqrt(sumI), math.Sqrt(sumJ)
-		for i := 0; i < 5; i++ {
+		for i := 0; i < n; i++ {
 			cI[i] /= sumI
 			cJ[i] /= sumJ
 		}
 
 		// cI, cJ 均值，偏差
 		sumI, sumJ = 0.0, 0.0
-		for i := 0; i < 5; i++ {
+		for i := 0; i < n; i++ {
 			sumI += cI[i]
 			sumJ += cJ[i]
 		}
-		meanI := sumI / 5.0
-		meanJ := sumJ / 5.0
-		diffI := make([]float64, 5, 5)
-		diffJ := make([]float64, 5, 5)
-		for i := 0; i < 5; i++ {
+		meanI := sumI / float64(n)
+		meanJ := sumJ / float64(n)
+		diffI := make([]float64, n)
+		diffJ := make([]float64, n)
+		for i := 0; i < n; i++ {
 			diffI[i] = cI[i] - meanI
 			diffJ[i] = cJ[i] - meanJ
 		}
 
 		// cI, cJ 协方差、标准差
 		covar := 0.0
-		for i := 0; i < 5; i++ {
+		for i := 0; i < n; i++ {
 			covar += diffI[i] * diffJ[i]
 		}
-		covar /= 4.0
+		covar /= float64(n - 1)
 		stdevI, stdevJ := 0.0, 0.0
-		for i := 0; i < 5; i++ {
+		for i := 0; i < n; i++ {
 			stdevI += diffI[i] * diffI[i]
 			stdevJ += diffJ[i] * diffJ[i]
 		}
-		stdevI /= (5.0 - 1.0)
+		stdevI /= float64(n - 1)
 		stdevI = math.Sqrt(stdevI)
-		stdevJ /= (5.0 - 1.0)
+		stdevJ /= float64(n - 1)
 		stdevJ = math.Sqrt(stdevJ)
 		var corr = 0.0
 		if stdevI > 0 && stdevJ > 0 {
@@ -201,7 +194,7 @@ type CorrResult struct {
 	corr float64
 }
 
-var limit = make(chan struct{}, 5)
+var limit = cfg.Limiter()
 
 func (c *CorrelationMatrix) MaxCorrFor(i int, corr chan<- CorrResult) {
 	limit <- struct{}{}
@@ -260,83 +253,23 @@ func (c *CorrelationMatrix) WriteToCSV(filename string) {
 	wr.Flush()
 }
 
-func LoadStockClose(stockcode string) (dates []string, closes []float64) {
-	var (
-		fname  string
-		f      *os.File
-		rd     *csv.Reader
-		err    error
-		record []string
-		close  float64
-	)
-	fname = path.Join(DIR, stockcode+".csv")
-	f, err = os.Open(fname)
+// LoadAndCleanData 读取两只股票的数据, 只保留两者都有的日期
+func LoadAndCleanData(f1name, f2name string) (dates []string, closes1, closes2 []float64) {
+	ctx := context.Background()
+	opts := &readr.Options{Header: true, Lenient: true, Adjust: cfg.Adjust}
+	fI, err := readr.Load(ctx, cfg.DataPath(f1name), opts)
 	if err != nil {
-		return nil, nil
+		return
 	}
-	defer f.Close()
-	rd = csv.NewReader(f)
-	rd.Comma = ','
-	rd.Comment = '#'
-	for {
-		record, err = rd.Read()
-		if err != nil {
-			break
-		}
-		dates = append(dates, record[0])
-		fmt.Sscanf(record[3], "%f", &close)
-		closes = append(closes, close)
+	fJ, err := readr.Load(ctx, cfg.DataPath(f2name), opts)
+	if err != nil {
+		return
 	}
-	return
-}
 
-func LoadAndCleanData(f1name, f2name string) (dates []string, closes1, closes2 []float64) {
-	datesI, closesI := LoadStockClose(f1name)
-	datesJ, closesJ := LoadStockClose(f2name)
 	// 数据清洗，将数据日期一一对应，删除无法比对的数据
-	var (
-		i, j int
-	)
-	i, j = 0, 0
-	for {
-		if i >= len(datesI) || j >= len(datesJ) {
-			break
-		}
-
-		if datesI[i] < datesJ[j] {
-			i++
-			continue
-		} else if datesI[i] > datesJ[j] {
-			j++
-			continue
-		}
-		dates = append(dates, datesI[i])
-		closes1 = append(closes1, closesI[i])
-		closes2 = append(closes2, closesJ[j])
-		i++
-		j++
-	}
-	return
-}
-
-const timeLayout = "2006-01-02"
-
-func NextMondayIndex(dates []string) int {
-	for i, date := range dates {
-		t, _ := time.Parse(timeLayout, date)
-		if t.Weekday() == time.Monday {
-			return i
-		}
-	}
-	return -1
-}
-
-func NextFridayIndex(dates []string) int {
-	for i, date := range dates {
-		t, _ := time.Parse(timeLayout, date)
-		if t.Weekday() == time.Friday {
-			return i
-		}
+	a, err := readr.Align([]*readr.Frame{fI, fJ}, readr.JoinInner, readr.FillNaN)
+	if err != nil {
+		return
 	}
-	return -1
+	return a.Dates, a.Frames[0].Closes, a.Frames[1].Closes
 }
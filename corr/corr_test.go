@@ -0,0 +1,40 @@
+package corr
+
+import (
+	"os"
+	"reflect"
+	"stockstat/config"
+	"testing"
+)
+
+func TestLoadAndCleanData(t *testing.T) {
+	cfg = config.Default()
+	cfg.DataDir = t.TempDir()
+	files := map[string]string{
+		"600000": "#date,open,high,close,low,volume,pow\n" +
+			"2024-01-02,10,10.5,10,9.8,1000,1\n" +
+			"2024-01-03,10,10.5,10.2,9.8,1000,1\n" +
+			"2024-01-04,bad,10.6,10.4,10.1,1200,1\n" +
+			"2024-01-05,10.4,10.9,10.8,10.3,1500,1\n",
+		"600036": "#date,open,high,close,low,volume,pow\n" +
+			"2024-01-03,30,30.5,30.2,29.8,1000,1\n" +
+			"2024-01-04,30,30.6,30.4,29.1,1200,1\n" +
+			"2024-01-05,30.4,30.9,30.8,30.3,1500,1\n" +
+			"2024-01-08,30.8,31,30.9,30.6,1800,1\n",
+	}
+	for code, data := range files {
+		if err := os.WriteFile(cfg.DataPath(code), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dates, c1, c2 := LoadAndCleanData("600000", "600036")
+	if want := []string{"2024-01-03", "2024-01-05"}; !reflect.DeepEqual(dates, want) {
+		t.Errorf("dates %v, want %v", dates, want)
+	}
+	if !reflect.DeepEqual(c1, []float64{10.2, 10.8}) || !reflect.DeepEqual(c2, []float64{30.2, 30.8}) {
+		t.Errorf("closes %v %v", c1, c2)
+	}
+	if dates, _, _ := LoadAndCleanData("600000", "000001"); dates != nil {
+		t.Errorf("missing file: dates %v", dates)
+	}
+}
@@ -0,0 +1,98 @@
+package corr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"stockstat/calendar"
+	"stockstat/catalog"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/roster"
+	"strconv"
+)
+
+var (
+	cfg            = config.Default() // 运行设置, 见package config
+	ResultFileName = "corr.csv"
+	Corr           = new(CorrelationMatrix)
+	cal            = calendar.Default() // 交易日历, 用于按周分组
+)
+
+// Header 是Run输出的表头
+var Header = []string{"code", "maxcode", "corr"}
+
+// 统计、计算相关性
+var completePercent = 0.0
+
+// Run 计算股票列表中每只股票与其后各股票的相关性, 把相关性最大的一对写到out,
+// 进度打印到标准错误
+func Run(conf *config.Config, out *cli.Output) error {
+	cfg = conf
+	limit = cfg.Limiter()
+	var err error
+	if cal, err = catalog.OpenCalendar(context.Background(), cfg.DataDir, cfg.Workers); err != nil {
+		return err
+	}
+
+	stockcodes, err := GetStockCodes()
+	if err != nil {
+		return err
+	}
+	if len(stockcodes) < 2 {
+		return fmt.Errorf("%s: need at least 2 stocks", cfg.RosterPath())
+	}
+	tw, err := out.NewTable(Header)
+	if err != nil {
+		return err
+	}
+	Corr.Init(stockcodes)
+
+	var corr = make(chan CorrResult)
+	first, end := 0, len(stockcodes)-2
+	for {
+		if first > end {
+			break
+		} else if first < end {
+			go Corr.MaxCorrFor(first, corr)
+			go Corr.MaxCorrFor(end, corr)
+		} else {
+			go Corr.MaxCorrFor(first, corr)
+		}
+		first++
+		end--
+	}
+
+	var c CorrResult
+	for i := 0; i < len(stockcodes)-1; i++ {
+		c = <-corr
+		if err == nil {
+			err = tw.Write([]string{Corr.StockCodes[c.i], Corr.StockCodes[c.j], strconv.FormatFloat(c.corr, 'f', 2, 64)})
+		}
+		fmt.Fprintf(os.Stderr, "finished: %.2f %%\n", float64(i+1)/float64(Corr.stocks-1)*100)
+	}
+	if cerr := tw.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// GetStockCodes 返回股票列表文件中的股票代码。数据目录中有清单时, 跳过没有数据或过时的股票。
+func GetStockCodes() ([]string, error) {
+	stocks, err := roster.Load(cfg.RosterPath())
+	if err != nil {
+		return nil, err
+	}
+	usable, skipped, err := catalog.Filter(cfg.DataDir, stocks.Codes())
+	if err != nil {
+		return nil, err
+	}
+	for code, why := range skipped {
+		fmt.Fprintln(os.Stderr, code, "skipped:", why)
+	}
+	codes := make([]string, len(usable))
+	for i, c := range usable {
+		codes[i] = c.String()
+	}
+	return codes, nil
+}
@@ -0,0 +1,69 @@
+// package csv2table 把数据目录中没有扩展名的表格文件(见stat/doc.md)转换为<name>.csv,
+// 转换成功后删除原文件。
+//
+// 表格文件是新浪的后复权数据, 转换时与sina2ifeng一样换算为不复权的价格,
+// 按数据目录的格式(readr.StockDataFormat)带标记写出, 不会再被sina2ifeng换算。
+package csv2table
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"stockstat/readr"
+	"stockstat/safefile"
+	"strings"
+)
+
+func FileList(path string) ([]string, error) {
+	fs := []string{}
+	err := filepath.Walk(path, func(path string, f os.FileInfo, err error) error {
+		if f == nil {
+			return err
+		}
+		if f.IsDir() {
+			return nil
+		}
+		if strings.Index(f.Name(), ".") == -1 {
+			fs = append(fs, f.Name())
+		}
+		return nil
+	})
+	return fs, err
+}
+
+// Run 转换目录root中的表格文件, 有文件转换失败时返回错误
+func Run(root string) error {
+	fs, err := FileList(root)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, fname := range fs {
+		frm := readr.ReadTable(path.Join(root, fname), false)
+		if frm == nil {
+			failed++
+			continue
+		}
+		frm.Adjustment = readr.AdjustBackward
+		if err := frm.Adjust(readr.AdjustNone); err != nil {
+			fmt.Fprintln(os.Stderr, fname, err)
+			failed++
+			continue
+		}
+		err := safefile.WriteFile(path.Join(root, fname+".csv"), func(w io.Writer) error {
+			return frm.WriteCSV(w, &readr.StockDataFormat)
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, fname, err)
+			failed++
+			continue
+		}
+		os.Remove(path.Join(root, fname))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(fs))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package csv2table
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"stockstat/readr"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	// stat/doc.md中的格式, 新浪的后复权价格
+	table := "2004-01-02\t16.006\t16.406\t16.160\t15.883\t11565225.000\t121756888.000\t1.539\n" +
+		"2004-01-05\t16.160\t16.929\t16.883\t16.083\t25313652.000\t270185216.000\t1.539\n"
+	if err := os.WriteFile(filepath.Join(root, "600000"), []byte(table), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "600000")); !os.IsNotExist(err) {
+		t.Errorf("table file not removed: %v", err)
+	}
+	path := filepath.Join(root, "600000.csv")
+	m, err := readr.ReadMarker(path)
+	if err != nil || m == nil || m.Adjust != readr.AdjustNone {
+		t.Fatalf("ReadMarker = %v, %v, want adjust=none", m, err)
+	}
+	frm, err := readr.Load(context.Background(), path, &readr.Options{Header: true, NoCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frm.Len() != 2 || frm.Opens[0] != 10.4 || frm.Closes[1] != 10.97 || frm.Power[0] != 1.539 || frm.Volumns[0] != 11565225 {
+		t.Errorf("converted %v %v %v %v, want raw prices", frm.Opens, frm.Closes, frm.Power, frm.Volumns)
+	}
+}
@@ -0,0 +1,106 @@
+// package events 从权值列推出各股票的除权除息事件, 供按权值段(两次除权之间)统计的各命令共用。
+//
+// 权值是累计的后复权因子, 除权日的权值大于前一天。相邻两行的权值相对变化超过MinChange时为一次事件,
+// 更小的变化视为权值的舍入误差。新旧权值之比不小于BonusRatio的为送转股、拆分或配股, 否则为现金分红。
+//
+// 权值缺失或不变的数据(如由旧的表格文件转换而来的)可以用Infer由价格推断送转股及拆分, 见Proposal。
+package events
+
+import (
+	"context"
+	"math"
+	"stockstat/readr"
+	"time"
+)
+
+// 事件的分类
+const (
+	KindDividend = "dividend" // 现金分红, 权值变化小
+	KindBonus    = "bonus"    // 送转股、拆分或配股, 权值变化大
+	KindReverse  = "reverse"  // 权值减小, 如缩股, 也可能是数据有误
+)
+
+// 缺省的规则
+const (
+	MinChange  = 0.005 // 权值的相对变化超过它时为除权
+	BonusRatio = 1.1   // 新旧权值之比不小于它时为送转股, 即至少10送1
+)
+
+// Event 是一次除权除息
+type Event struct {
+	Date     string
+	Time     time.Time
+	Index    int     // 除权日在数据中的行号
+	OldPower float64 // 前一天的权值
+	NewPower float64 // 除权日的权值
+	Ratio    float64 // NewPower/OldPower
+	Kind     string
+	// Cash 是按现金分红推算的每股派息(元): 前一天的收盘价*(1-1/Ratio), 仅KindDividend有,
+	// 数据不是不复权的价格时为0
+	Cash float64
+}
+
+// Changed 报告权值由old变为cur是否为除权
+func Changed(old, cur float64) bool {
+	return cur != old && math.Abs(cur-old)/old > MinChange
+}
+
+// Classify 按新旧权值之比ratio分类
+func Classify(ratio float64) string {
+	switch {
+	case ratio < 1:
+		return KindReverse
+	case ratio >= BonusRatio:
+		return KindBonus
+	}
+	return KindDividend
+}
+
+// Detect 返回frm中的各次除权, 按日期升序。frm没有权值列时没有事件。
+func Detect(frm *readr.Frame) []Event {
+	if !frm.Has(readr.ColPower) {
+		return nil
+	}
+	var evs []Event
+	for i := 1; i < frm.Len(); i++ {
+		p0, p1 := frm.Power[i-1], frm.Power[i]
+		if !Changed(p0, p1) {
+			continue
+		}
+		ev := Event{
+			Date: frm.Dates[i], Index: i,
+			OldPower: p0, NewPower: p1, Ratio: p1 / p0,
+		}
+		if i < len(frm.Times) {
+			ev.Time = frm.Times[i]
+		} else {
+			ev.Time, _ = readr.ParseDate(ev.Date)
+		}
+		ev.Kind = Classify(ev.Ratio)
+		if ev.Kind == KindDividend && frm.Adjustment == readr.AdjustNone {
+			ev.Cash = math.Round(frm.Closes[i-1]*(1-1/ev.Ratio)*1000) / 1000
+		}
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+// Segments 返回按事件evs把n行数据分成的各权值段的起始行号, 最后加上n,
+// 即第k段为[segs[k], segs[k+1])。
+func Segments(evs []Event, n int) []int {
+	segs := make([]int, 0, len(evs)+2)
+	segs = append(segs, 0)
+	for _, ev := range evs {
+		segs = append(segs, ev.Index)
+	}
+	return append(segs, n)
+}
+
+// Load 读取数据文件path并返回其中的各次除权
+func Load(ctx context.Context, path string) ([]Event, error) {
+	frm, err := readr.Load(ctx, path, &readr.Options{Header: true, Lenient: true})
+	if err != nil {
+		return nil, err
+	}
+	return Detect(frm), nil
+}
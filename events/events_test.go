@@ -0,0 +1,142 @@
+package events
+
+import (
+	"bytes"
+	"math"
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"testing"
+	"time"
+)
+
+// powFrame 返回自2024-01-02起每天一行的不复权数据, 开高收低都为closes[i], 权值为powers[i]
+func powFrame(closes, powers []float64) *readr.Frame {
+	cols := []readr.Column{readr.ColOpen, readr.ColHigh, readr.ColClose, readr.ColLow, readr.ColVolumn}
+	if powers != nil {
+		cols = append(cols, readr.ColPower)
+	}
+	frm := readr.NewFrame(cols, len(closes))
+	t0 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i, c := range closes {
+		t := t0.AddDate(0, 0, i)
+		bar := readr.Bar{Date: t.Format(readr.DateLayout), Time: t, Open: c, High: c, Close: c, Low: c, Volumn: 100}
+		if powers != nil {
+			bar.Power = powers[i]
+		}
+		frm.Append(&bar)
+	}
+	return frm
+}
+
+func TestDetect(t *testing.T) {
+	type want struct {
+		index int
+		kind  string
+		cash  float64
+	}
+	tests := []struct {
+		name   string
+		closes []float64
+		powers []float64
+		adjust readr.Adjustment
+		want   []want
+	}{
+		{"no pow", []float64{10, 10, 10}, nil, readr.AdjustNone, nil},
+		{"constant", []float64{10, 10, 10}, []float64{1.5, 1.5, 1.5}, readr.AdjustNone, nil},
+		{"rounding", []float64{10, 10, 10}, []float64{1, 1.003, 1.001}, readr.AdjustNone, nil},
+		{"dividend", []float64{10, 9.8, 9.8}, []float64{1, 1, 1.02}, readr.AdjustNone,
+			[]want{{2, KindDividend, 0.192}}},
+		{"dividend adjusted", []float64{10, 9.8, 9.8}, []float64{1, 1, 1.02}, readr.AdjustBackward,
+			[]want{{2, KindDividend, 0}}},
+		{"bonus and dividend", []float64{10, 5, 5, 5}, []float64{1, 2, 2, 2.04}, readr.AdjustNone,
+			[]want{{1, KindBonus, 0}, {3, KindDividend, 0.098}}},
+		{"bonus ratio 1.1", []float64{11, 10}, []float64{1, 1.1}, readr.AdjustNone, []want{{1, KindBonus, 0}}},
+		{"reverse", []float64{5, 10}, []float64{2, 1}, readr.AdjustNone, []want{{1, KindReverse, 0}}},
+	}
+	for _, tt := range tests {
+		frm := powFrame(tt.closes, tt.powers)
+		frm.Adjustment = tt.adjust
+		evs := Detect(frm)
+		if len(evs) != len(tt.want) {
+			t.Errorf("%s: %d events %+v, want %d", tt.name, len(evs), evs, len(tt.want))
+			continue
+		}
+		for i, w := range tt.want {
+			ev := evs[i]
+			if ev.Index != w.index || ev.Kind != w.kind || ev.Cash != w.cash {
+				t.Errorf("%s: event %d = %+v, want index %d, %s, cash %v", tt.name, i, ev, w.index, w.kind, w.cash)
+			}
+			if ev.Date != frm.Dates[w.index] || !ev.Time.Equal(frm.Times[w.index]) ||
+				ev.OldPower != tt.powers[w.index-1] || ev.NewPower != tt.powers[w.index] ||
+				math.Abs(ev.Ratio-ev.NewPower/ev.OldPower) > 1e-12 {
+				t.Errorf("%s: event %d = %+v", tt.name, i, ev)
+			}
+		}
+		if got := Constant(frm); got != (len(tt.want) == 0) {
+			t.Errorf("%s: Constant = %v", tt.name, got)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for _, tt := range []struct {
+		ratio float64
+		kind  string
+	}{{0.5, KindReverse}, {1.006, KindDividend}, {1.0999, KindDividend}, {1.1, KindBonus}, {2, KindBonus}} {
+		if got := Classify(tt.ratio); got != tt.kind {
+			t.Errorf("Classify(%v) = %s, want %s", tt.ratio, got, tt.kind)
+		}
+	}
+	if Changed(1, 1.005) || !Changed(1, 1.0051) || Changed(2, 2) {
+		t.Error("Changed does not follow MinChange")
+	}
+}
+
+func TestSegments(t *testing.T) {
+	evs := []Event{{Index: 3}, {Index: 7}}
+	got := Segments(evs, 10)
+	want := []int{0, 3, 7, 10}
+	if len(got) != len(want) {
+		t.Fatalf("Segments = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Segments = %v, want %v", got, want)
+		}
+	}
+	if got := Segments(nil, 5); len(got) != 2 || got[0] != 0 || got[1] != 5 {
+		t.Errorf("Segments(nil, 5) = %v, want [0 5]", got)
+	}
+}
+
+func TestTableRoundTrip(t *testing.T) {
+	frm := powFrame([]float64{10, 5, 5, 5}, []float64{1, 2, 2, 2.04})
+	table := Table{
+		stockcode.MustParse("600000"): Detect(frm),
+		stockcode.MustParse("000001"): Detect(powFrame([]float64{10, 9.8}, []float64{1, 1.02})),
+	}
+	var buf bytes.Buffer
+	if err := table.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for code, evs := range table {
+		if len(got[code]) != len(evs) {
+			t.Fatalf("%s: read %d events, want %d", code, len(got[code]), len(evs))
+		}
+		for i, ev := range evs {
+			g := got[code][i]
+			ev.Index = -1 // 不保存在文件中
+			if g.Date != ev.Date || !g.Time.Equal(ev.Time) || g.Kind != ev.Kind || g.Cash != ev.Cash ||
+				g.OldPower != ev.OldPower || g.NewPower != ev.NewPower || math.Abs(g.Ratio-ev.Ratio) > 1e-6 || g.Index != -1 {
+				t.Errorf("%s: event %d read as %+v, want %+v", code, i, g, ev)
+			}
+		}
+	}
+	if _, err := Read(bytes.NewBufferString("#v2,code,date\n")); err == nil {
+		t.Error("Read of version 2 succeeded")
+	}
+}
@@ -0,0 +1,208 @@
+package events
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"stockstat/readr"
+	"stockstat/safefile"
+	"stockstat/stockcode"
+	"strconv"
+	"strings"
+)
+
+// FileName 是数据目录中事件文件的文件名
+const FileName = "events.csv"
+
+// Version 是Write写出的表头版本
+const Version = 1
+
+// Header 是事件文件及Record的各列
+var Header = []string{"code", "date", "old", "new", "ratio", "kind", "cash"}
+
+// Table 是各股票的事件, 每只股票的事件按日期升序
+type Table map[stockcode.Code][]Event
+
+// ReadFile 读取事件文件path
+func ReadFile(path string) (Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return t, nil
+}
+
+// Read 从r读取事件表。Index不保存在文件中, 读出时为-1。
+func Read(r io.Reader) (Table, error) {
+	rd := csv.NewReader(r)
+	rd.FieldsPerRecord = -1
+	rd.TrimLeadingSpace = true
+	t := Table{}
+	for first := true; ; first = false {
+		record, err := rd.Read()
+		if err == io.EOF {
+			return t, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := rd.FieldPos(0)
+		if strings.HasPrefix(record[0], "#") {
+			if first && !strings.HasPrefix(record[0], "#v1") {
+				return nil, fmt.Errorf("line %d: unsupported header %q", line, strings.Join(record, ","))
+			}
+			continue
+		}
+		code, ev, err := parseEvent(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		t[code] = append(t[code], ev)
+	}
+}
+
+func parseEvent(record []string) (stockcode.Code, Event, error) {
+	ev := Event{Index: -1}
+	if len(record) < len(Header) {
+		return stockcode.Code{}, ev, fmt.Errorf("%d fields, want %d", len(record), len(Header))
+	}
+	code, err := stockcode.Parse(record[0])
+	if err != nil {
+		return code, ev, err
+	}
+	ev.Date, ev.Kind = record[1], record[5]
+	if ev.Time, err = readr.ParseDate(ev.Date); err != nil {
+		return code, ev, err
+	}
+	fields := []struct {
+		col int
+		v   *float64
+	}{{2, &ev.OldPower}, {3, &ev.NewPower}, {4, &ev.Ratio}, {6, &ev.Cash}}
+	for _, f := range fields {
+		if *f.v, err = strconv.ParseFloat(record[f.col], 64); err != nil {
+			return code, ev, fmt.Errorf("%s: %v", Header[f.col], err)
+		}
+	}
+	return code, ev, nil
+}
+
+// Save 把事件表写入文件path, 写入失败时原文件不变
+func (t Table) Save(path string) error {
+	return safefile.WriteFile(path, t.Write)
+}
+
+// Codes 返回t中的股票代码, 升序
+func (t Table) Codes() []stockcode.Code {
+	codes := make([]stockcode.Code, 0, len(t))
+	for code := range t {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].String() < codes[j].String() })
+	return codes
+}
+
+// Write 按股票代码升序写出事件表, 没有事件的股票不写出
+func (t Table) Write(w io.Writer) error {
+	wr := csv.NewWriter(w)
+	wr.Write(append([]string{fmt.Sprintf("#v%d", Version)}, Header...))
+	for _, code := range t.Codes() {
+		for _, ev := range t[code] {
+			wr.Write(Record(code, &ev))
+		}
+	}
+	wr.Flush()
+	return wr.Error()
+}
+
+// Record 返回股票code的事件ev的一行
+func Record(code stockcode.Code, ev *Event) []string {
+	return []string{
+		code.String(), ev.Date,
+		formatFloat(ev.OldPower), formatFloat(ev.NewPower), strconv.FormatFloat(ev.Ratio, 'f', 6, 64),
+		ev.Kind, formatFloat(ev.Cash),
+	}
+}
+
+func formatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+// ProposalHeader 是送转推断表(见RunInfer)的各列。accept为yes的行由RunApply写入数据文件,
+// 人工检查时可以修改accept及ratio。
+var ProposalHeader = []string{"code", "date", "prev_close", "open", "low", "ratio", "label", "confidence", "accept"}
+
+// ProposalRecord 返回股票code的推断p的一行
+func ProposalRecord(code stockcode.Code, p *Proposal, accept bool) []string {
+	yes := "no"
+	if accept {
+		yes = "yes"
+	}
+	return []string{
+		code.String(), p.Date,
+		formatFloat(p.PrevClose), formatFloat(p.Open), formatFloat(p.Low), formatFloat(p.Ratio),
+		p.Label, strconv.FormatFloat(p.Confidence, 'f', 2, 64), yes,
+	}
+}
+
+// ReadProposals 读取csv格式的送转推断表path中accept为yes的行, 按列名识别各列, 只用到code, date, ratio及accept
+func ReadProposals(path string) (map[stockcode.Code][]Proposal, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rd := csv.NewReader(f)
+	rd.FieldsPerRecord = -1
+	rd.TrimLeadingSpace = true
+	header, err := rd.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"code", "date", "ratio", "accept"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("%s: no %s column", path, name)
+		}
+	}
+	props := map[stockcode.Code][]Proposal{}
+	for {
+		record, err := rd.Read()
+		if err == io.EOF {
+			return props, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		line, _ := rd.FieldPos(0)
+		field := func(name string) string {
+			if i := col[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		switch strings.ToLower(field("accept")) {
+		case "yes", "y", "true", "1":
+		default:
+			continue
+		}
+		code, err := stockcode.Parse(field("code"))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		p := Proposal{Date: field("date"), Index: -1, Label: field("label")}
+		if p.Ratio, err = strconv.ParseFloat(field("ratio"), 64); err != nil {
+			return nil, fmt.Errorf("%s:%d: ratio: %v", path, line, err)
+		}
+		props[code] = append(props[code], p)
+	}
+}
@@ -0,0 +1,136 @@
+package events
+
+import (
+	"fmt"
+	"math"
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"strings"
+	"time"
+)
+
+// Proposal 是由价格推断出的一次送转股或拆分, 用于权值缺失或不变的数据。
+// 不复权的价格在除权日跌破跌停价而又不能由涨跌幅限制解释时, 即推断为除权,
+// 比例取使该日各价格回到涨跌幅限制以内的常见送转比例。现金分红引起的价格变化在涨跌幅限制以内, 推断不出。
+type Proposal struct {
+	Date       string
+	Index      int     // 在数据中的行号
+	PrevClose  float64 // 前一天的收盘价
+	Open, Low  float64
+	Ratio      float64 // 推断的新旧权值之比, 推断不出比例时为前一天收盘价与开盘价之比
+	Label      string  // 如"10送转10", 推断不出比例时为LabelUnknown
+	Confidence float64 // 0至1, 见Infer
+}
+
+// LabelUnknown 是推断不出送转比例时的Label
+const LabelUnknown = "unknown"
+
+// bonusShares 是常见的每10股送转股数, 10送转10即1拆2
+var bonusShares = []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 12, 13, 14, 15, 16, 18, 20, 25, 30, 40, 50}
+
+// chiNextReform 是创业板涨跌幅限制改为20%的第一天
+var chiNextReform = time.Date(2020, 8, 24, 0, 0, 0, 0, time.UTC)
+
+// Band 返回板块board的股票在日期t的涨跌幅限制, st为true表示ST股票
+func Band(board stockcode.Board, st bool, t time.Time) float64 {
+	switch {
+	case board == stockcode.BoardBSE:
+		return 0.3
+	case board == stockcode.BoardStar:
+		return 0.2
+	case board == stockcode.BoardChiNext && !t.Before(chiNextReform):
+		return 0.2
+	case st:
+		return 0.05
+	}
+	return 0.1
+}
+
+// IsST 报告股票名是否表示ST股票
+func IsST(name string) bool {
+	return strings.Contains(strings.ToUpper(name), "ST")
+}
+
+// bandSlack 是涨跌停价按分取整的误差(元)
+const bandSlack = 0.011
+
+// Infer 在不复权的数据frm中寻找送转股及拆分, 返回按日期升序的各推断。
+// 股票的涨跌幅限制见Band; 第一行没有前一天, 不参与推断。
+//
+// 某日最低价低于跌停价时, 依次试各常见比例c: 各价格乘以c后都在涨跌幅限制以内的比例为可行的,
+// 其中乘以c后开盘价与前一天收盘价最接近的为推断的比例。可信度为该比例的吻合度乘以(1-次优比例的吻合度),
+// 吻合度为1-|换算后的开盘涨跌幅|/(涨跌幅限制/2), 不小于0; 没有可行的比例时可信度为0, 可能是数据有误。
+func Infer(frm *readr.Frame, board stockcode.Board, st bool) []Proposal {
+	var props []Proposal
+	for i := 1; i < frm.Len(); i++ {
+		prev, open, low, high := frm.Closes[i-1], frm.Opens[i], frm.Lows[i], frm.Highs[i]
+		if !(prev > 0) || !(low > 0) {
+			continue
+		}
+		t := frm.Times[i]
+		band := Band(board, st, t)
+		if low >= prev*(1-band)-bandSlack {
+			continue
+		}
+		p := Proposal{
+			Date: frm.Dates[i], Index: i,
+			PrevClose: prev, Open: open, Low: low,
+			Ratio: math.Round(prev/open*100) / 100, Label: LabelUnknown,
+		}
+		best, second := 0.0, 0.0
+		for _, k := range bonusShares {
+			c := 1 + k/10
+			if c*low < prev*(1-band)-bandSlack || c*high > prev*(1+band)+bandSlack {
+				continue
+			}
+			fit := 1 - math.Abs(c*open/prev-1)/(band/2)
+			if fit < 0 {
+				fit = 0
+			}
+			switch {
+			case fit > best:
+				best, second = fit, best
+				p.Ratio, p.Label = c, fmt.Sprintf("10送转%g", k)
+			case fit > second:
+				second = fit
+			}
+		}
+		p.Confidence = math.Round(best*(1-second)*100) / 100
+		props = append(props, p)
+	}
+	return props
+}
+
+// Rebuild 返回按props重建的frm的权值: 第一行为base, 之后每个推断日乘以其Ratio。
+// 按日期对应props与frm的行, frm中没有的日期为错误。
+func Rebuild(frm *readr.Frame, props []Proposal, base float64) ([]float64, error) {
+	ratios := make(map[time.Time]float64, len(props))
+	for _, p := range props {
+		t, err := readr.ParseDate(p.Date)
+		if err != nil {
+			return nil, err
+		}
+		ratios[t] = p.Ratio
+	}
+	power := make([]float64, frm.Len())
+	p := base
+	for i := range power {
+		if r, ok := ratios[frm.Times[i]]; ok {
+			if !(r > 0) {
+				return nil, fmt.Errorf("%s: bad ratio %v", frm.Dates[i], r)
+			}
+			p *= r
+			delete(ratios, frm.Times[i])
+		}
+		power[i] = p
+	}
+	for t := range ratios {
+		return nil, fmt.Errorf("%s: no such date in data", t.Format(readr.DateLayout))
+	}
+	return power, nil
+}
+
+// Constant 报告frm的权值是否缺失或不变, 即可以由Infer重建
+func Constant(frm *readr.Frame) bool {
+	return len(Detect(frm)) == 0
+}
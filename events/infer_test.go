@@ -0,0 +1,127 @@
+package events
+
+import (
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"testing"
+	"time"
+)
+
+// ohlc 是测试用的一天: 开盘, 最高, 最低, 收盘
+type ohlc [4]float64
+
+// priceFrame 返回自start起每天一行的不复权数据, 没有权值
+func priceFrame(start string, days []ohlc) *readr.Frame {
+	frm := readr.NewFrame([]readr.Column{readr.ColOpen, readr.ColHigh, readr.ColClose, readr.ColLow, readr.ColVolumn}, len(days))
+	t0, err := readr.ParseDate(start)
+	if err != nil {
+		panic(err)
+	}
+	for i, d := range days {
+		t := t0.AddDate(0, 0, i)
+		frm.Append(&readr.Bar{Date: t.Format(readr.DateLayout), Time: t, Open: d[0], High: d[1], Low: d[2], Close: d[3], Volumn: 100})
+	}
+	return frm
+}
+
+func TestBand(t *testing.T) {
+	before, after := time.Date(2020, 8, 21, 0, 0, 0, 0, time.UTC), chiNextReform
+	tests := []struct {
+		board stockcode.Board
+		st    bool
+		t     time.Time
+		band  float64
+	}{
+		{stockcode.BoardMain, false, after, 0.1},
+		{stockcode.BoardMain, true, after, 0.05},
+		{stockcode.BoardChiNext, false, before, 0.1},
+		{stockcode.BoardChiNext, true, before, 0.05},
+		{stockcode.BoardChiNext, false, after, 0.2},
+		{stockcode.BoardChiNext, true, after, 0.2},
+		{stockcode.BoardStar, true, before, 0.2},
+		{stockcode.BoardBSE, false, after, 0.3},
+	}
+	for _, tt := range tests {
+		if got := Band(tt.board, tt.st, tt.t); got != tt.band {
+			t.Errorf("Band(%s, %v, %s) = %v, want %v", tt.board, tt.st, tt.t.Format(readr.DateLayout), got, tt.band)
+		}
+	}
+	if !IsST("*ST康美") || !IsST("st东海") || IsST("浦发银行") {
+		t.Error("IsST")
+	}
+}
+
+func TestInfer(t *testing.T) {
+	const prev = 10.0
+	tests := []struct {
+		name  string
+		board stockcode.Board
+		st    bool
+		start string
+		day   ohlc // 前一天收盘为prev
+		found bool
+		label string
+		ratio float64
+		conf  float64
+	}{
+		// 除权后开盘正好是前一天收盘的一半, 只有10送转10可行且吻合
+		{"bonus 10", stockcode.BoardMain, false, "2024-01-02", ohlc{5, 5.1, 4.95, 5.05}, true, "10送转10", 2, 1},
+		// 跌停不是除权
+		{"limit down", stockcode.BoardMain, false, "2024-01-02", ohlc{9.5, 9.6, 9, 9}, false, "", 0, 0},
+		// 10送转4与10送转5都可行, 开盘与两者都差得不远, 可信度低
+		{"ambiguous", stockcode.BoardMain, false, "2024-01-02", ohlc{6.9, 7, 6.85, 6.95}, true, "10送转4", 1.4, 0.22},
+		// 振幅太大, 没有可行的比例, 可能是数据有误
+		{"no ratio", stockcode.BoardMain, false, "2024-01-02", ohlc{8, 9, 4, 8}, true, LabelUnknown, 1.25, 0},
+		// 创业板改革后涨跌幅限制为20%, 跌15%不是除权; 改革前是
+		{"chinext after", stockcode.BoardChiNext, false, "2024-01-02", ohlc{8.6, 8.7, 8.5, 8.6}, false, "", 0, 0},
+		{"chinext before", stockcode.BoardChiNext, false, "2019-01-02", ohlc{8.6, 8.7, 8.5, 8.6}, true, "10送转2", 1.2, 0.36},
+		// ST股票的涨跌幅限制为5%
+		{"st", stockcode.BoardMain, true, "2024-01-02", ohlc{9.45, 9.5, 9.4, 9.45}, true, LabelUnknown, 1.06, 0},
+		{"not st", stockcode.BoardMain, false, "2024-01-02", ohlc{9.45, 9.5, 9.4, 9.45}, false, "", 0, 0},
+	}
+	for _, tt := range tests {
+		frm := priceFrame(tt.start, []ohlc{{prev, prev, prev, prev}, tt.day})
+		props := Infer(frm, tt.board, tt.st)
+		if !tt.found {
+			if len(props) != 0 {
+				t.Errorf("%s: proposals %+v, want none", tt.name, props)
+			}
+			continue
+		}
+		if len(props) != 1 {
+			t.Errorf("%s: proposals %+v, want one", tt.name, props)
+			continue
+		}
+		p := props[0]
+		if p.Index != 1 || p.Date != frm.Dates[1] || p.PrevClose != prev || p.Open != tt.day[0] || p.Low != tt.day[2] {
+			t.Errorf("%s: proposal %+v", tt.name, p)
+		}
+		if p.Label != tt.label || p.Ratio != tt.ratio || p.Confidence != tt.conf {
+			t.Errorf("%s: %s ratio %v confidence %v, want %s %v %v", tt.name, p.Label, p.Ratio, p.Confidence, tt.label, tt.ratio, tt.conf)
+		}
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	frm := priceFrame("2024-01-02", []ohlc{{10, 10, 10, 10}, {10, 10, 10, 10}, {5, 5, 5, 5}, {5, 5, 5, 5}})
+	props := Infer(frm, stockcode.BoardMain, false)
+	if len(props) != 1 {
+		t.Fatalf("proposals %+v, want one", props)
+	}
+	power, err := Rebuild(frm, props, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{1.5, 1.5, 3, 3}
+	for i := range want {
+		if power[i] != want[i] {
+			t.Fatalf("Rebuild = %v, want %v", power, want)
+		}
+	}
+	if _, err := Rebuild(frm, []Proposal{{Date: "2024-02-01", Ratio: 2}}, 1); err == nil {
+		t.Error("Rebuild with a date not in data succeeded")
+	}
+	if _, err := Rebuild(frm, []Proposal{{Date: "2024-01-03", Ratio: 0}}, 1); err == nil {
+		t.Error("Rebuild with ratio 0 succeeded")
+	}
+}
@@ -0,0 +1,223 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/readr"
+	"stockstat/roster"
+	"stockstat/safefile"
+	"stockstat/stockcode"
+	"strconv"
+	"sync"
+)
+
+// Path 返回数据目录dir中的事件文件
+func Path(dir string) string {
+	return filepath.Join(dir, FileName)
+}
+
+// selectStocks 返回codes中的各股票, codes为空时返回股票列表中的全部股票。
+// 股票列表中没有(或读不出股票列表)的股票只有代码及由代码推断的板块。
+func selectStocks(c *config.Config, codes []string) (roster.List, error) {
+	stocks, err := roster.Load(c.RosterPath())
+	if len(codes) == 0 {
+		return stocks, err
+	}
+	var list roster.List
+	for _, s := range codes {
+		code, err := stockcode.Parse(s)
+		if err != nil {
+			return nil, cli.Usagef("%v", err)
+		}
+		if st := stocks.Lookup(s); st != nil {
+			list = append(list, *st)
+		} else {
+			list = append(list, roster.Stock{Code: code, Board: code.Board()})
+		}
+	}
+	return list, nil
+}
+
+// forEach 按c.Workers的并发数对各股票调用fn
+func forEach(c *config.Config, stocks roster.List, fn func(i int, s *roster.Stock)) {
+	limit := c.Limiter()
+	var wg sync.WaitGroup
+	for i := range stocks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			fn(i, &stocks[i])
+		}(i)
+	}
+	wg.Wait()
+}
+
+// Run 推出codes(为空时为股票列表中的全部股票)各股票的除权事件, 写到out,
+// 并更新数据目录中的事件文件: 这些股票的事件被替换, 其他股票的不变。
+// 读不出数据的股票被跳过并记入日志。
+func Run(c *config.Config, out *cli.Output, codes []string) error {
+	stocks, err := selectStocks(c, codes)
+	if err != nil {
+		return err
+	}
+	found := make([][]Event, len(stocks))
+	ok := make([]bool, len(stocks))
+	ctx := context.Background()
+	forEach(c, stocks, func(i int, s *roster.Stock) {
+		evs, err := Load(ctx, c.DataPath(s.Code.String()))
+		if err != nil {
+			log.Println(s.Code, "skipped:", err)
+			return
+		}
+		found[i], ok[i] = evs, true
+	})
+
+	path := Path(c.DataDir)
+	t, err := ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t, err = Table{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	var records [][]string
+	for i := range stocks {
+		if !ok[i] {
+			continue
+		}
+		code := stocks[i].Code
+		delete(t, code)
+		if len(found[i]) > 0 {
+			t[code] = found[i]
+		}
+		for k := range found[i] {
+			records = append(records, Record(code, &found[i][k]))
+		}
+	}
+	if err := out.WriteTable(Header, records); err != nil {
+		return err
+	}
+	return t.Save(path)
+}
+
+// RunInfer 在codes(为空时为股票列表中的全部股票)中权值缺失或不变的股票的数据中推断送转股及拆分(见Infer),
+// 把推断表写到out。可信度不低于minConfidence且推断出比例的行accept为yes。
+// 人工检查推断表后用RunApply写入数据文件。读不出数据的股票被跳过并记入日志。
+func RunInfer(c *config.Config, out *cli.Output, codes []string, minConfidence float64) error {
+	stocks, err := selectStocks(c, codes)
+	if err != nil {
+		return err
+	}
+	found := make([][]Proposal, len(stocks))
+	ctx := context.Background()
+	forEach(c, stocks, func(i int, s *roster.Stock) {
+		frm, err := readr.Load(ctx, c.DataPath(s.Code.String()), &readr.Options{Header: true, Lenient: true})
+		if err != nil {
+			log.Println(s.Code, "skipped:", err)
+			return
+		}
+		if Constant(frm) {
+			found[i] = Infer(frm, s.Board, IsST(s.Name))
+		}
+	})
+	var records [][]string
+	for i := range stocks {
+		for k := range found[i] {
+			p := &found[i][k]
+			accept := p.Confidence >= minConfidence && p.Label != LabelUnknown
+			records = append(records, ProposalRecord(stocks[i].Code, p, accept))
+		}
+	}
+	return out.WriteTable(ProposalHeader, records)
+}
+
+// ApplyHeader 是RunApply输出的表头
+var ApplyHeader = []string{"code", "events", "changed", "error"}
+
+// RunApply 把推断表path中accept为yes的送转写入各股票的数据文件: 按其比例重建权值(见Rebuild),
+// 第一行的权值不变(没有权值时为1), 经由readr.Merge写回。各股票的结果写到out。
+// 权值已有变化的数据文件不改写, 以免重复写入; 不是不复权价格的数据文件(没有标记, 见readr.IsLegacy)不改写。
+func RunApply(c *config.Config, out *cli.Output, path string) error {
+	props, err := ReadProposals(path)
+	if err != nil {
+		return err
+	}
+	codes := make([]stockcode.Code, 0, len(props))
+	for code := range props {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].String() < codes[j].String() })
+
+	ctx := context.Background()
+	failed := 0
+	records := make([][]string, len(codes))
+	for i, code := range codes {
+		changed, err := apply(ctx, c.DataPath(code.String()), props[code])
+		msg := ""
+		if err != nil {
+			failed++
+			msg = err.Error()
+		}
+		records[i] = []string{code.String(), strconv.Itoa(len(props[code])), strconv.Itoa(changed), msg}
+	}
+	if err := out.WriteTable(ApplyHeader, records); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d stocks failed", failed, len(codes))
+	}
+	return nil
+}
+
+// apply 按props重建数据文件path的权值, 返回改变的行数
+func apply(ctx context.Context, path string, props []Proposal) (int, error) {
+	m, err := readr.ReadMarker(path)
+	if err != nil {
+		return 0, err
+	}
+	if m == nil {
+		legacy, err := readr.IsLegacy(path)
+		if err != nil {
+			return 0, err
+		}
+		if !legacy {
+			return 0, fmt.Errorf("%s: not converted to raw prices, run stockstat sina first", path)
+		}
+	}
+	old, err := readr.Load(ctx, path, &readr.Options{Header: true, NoCache: true})
+	if err != nil {
+		return 0, err
+	}
+	if !Constant(old) {
+		return 0, fmt.Errorf("%s: pow already changes, not rebuilt", path)
+	}
+	base := 1.0
+	if old.Has(readr.ColPower) && old.Len() > 0 && old.Power[0] > 0 {
+		base = old.Power[0]
+	}
+	power, err := Rebuild(old, props, base)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", path, err)
+	}
+	add := *old
+	add.Power = power
+	frm, report, err := readr.Merge(old, &add, readr.PolicyReplace)
+	if err != nil {
+		return 0, err
+	}
+	// 总是带标记写出, 旧版换算过的文件也由此加上标记, 以免被当作新浪的后复权数据再换算一次
+	err = safefile.WriteFile(path, func(w io.Writer) error {
+		return frm.WriteCSV(w, &readr.StockDataFormat)
+	})
+	return report.Changed, err
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"strings"
+	"testing"
+)
+
+func TestRunApply(t *testing.T) {
+	const rows = "2024-01-02,10.000,10.000,10.000,10.000,100,1.500\r\n" +
+		"2024-01-03,10.000,10.000,10.000,10.000,100,1.500\r\n" +
+		"2024-01-04,5.000,5.100,5.050,4.950,200,1.500\r\n"
+	tests := []struct {
+		name, data string
+		err        string // 错误信息的一部分, 为空表示成功
+	}{
+		{"legacy", "#date,open,high,cloe,low,volumn,pow\r\n" + rows, ""},
+		{"marked", "#stockstat v1 adjust=none\r\n#date,open,high,close,low,volume,pow\r\n" + rows, ""},
+		{"sina", "date,open,high,close,low,volume,amount,pow\r\n" +
+			"2024-01-02,15.000,15.000,15.000,15.000,100,1500,1.500\r\n", "not converted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.Default()
+			c.DataDir = t.TempDir()
+			path := c.DataPath("600000")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			props := filepath.Join(c.DataDir, "infer.csv")
+			f, err := os.Create(props)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := csv.NewWriter(f)
+			w.Write(ProposalHeader)
+			w.Write(ProposalRecord(stockcode.MustParse("600000"), &Proposal{Date: "2024-01-04", Ratio: 2, Label: "10送转10"}, true))
+			w.Flush()
+			f.Close()
+
+			out := &cli.Output{Format: cli.FormatCSV, Path: filepath.Join(c.DataDir, "apply.csv")}
+			err = RunApply(c, out, props)
+			if tt.err != "" {
+				b, _ := os.ReadFile(out.Path)
+				if err == nil || !strings.Contains(string(b), tt.err) {
+					t.Fatalf("RunApply = %v, report %s, want %q", err, b, tt.err)
+				}
+				if b, _ := os.ReadFile(path); string(b) != tt.data {
+					t.Errorf("file changed:\n%s", b)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 改写后仍被识别为不复权的数据, 见sina2ifeng及update
+			m, err := readr.ReadMarker(path)
+			if err != nil || m == nil || m.Adjust != readr.AdjustNone {
+				t.Fatalf("ReadMarker = %v, %v, want adjust=none", m, err)
+			}
+			frm, err := readr.Load(context.Background(), path, &readr.Options{Header: true, NoCache: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if frm.Closes[2] != 5.05 || frm.Power[1] != 1.5 || frm.Power[2] != 3 {
+				t.Errorf("closes %v, pow %v, want raw prices and pow 1.5, 1.5, 3", frm.Closes, frm.Power)
+			}
+			// 再次写入时权值已有变化, 不重复写入
+			if err := RunApply(c, out, props); err == nil {
+				t.Error("second RunApply succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// package howdist 统计一只股票各权值段日涨跌幅的分布。
+package howdist
+
+import (
+	"context"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/events"
+	"stockstat/readr"
+	"strconv"
+
+	"github.com/gonum/stat"
+)
+
+// cfg 是运行设置, 见package config
+var cfg = config.Default()
+
+// Header 是Run输出的表头
+var Header = []string{"mean", "stddev", "numbers"}
+
+// Run 把股票stockcode每个权值段日涨跌幅的均值, 标准差及天数写到out
+func Run(c *config.Config, out *cli.Output, stockcode string) error {
+	cfg = c
+	xs, dates, err := LoadStockClose(stockcode)
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for i := 0; i < len(dates)-1; i++ {
+		y := xs[dates[i]:dates[i+1]]
+		x := DeltaPrices(y)
+		rows = append(rows, []string{
+			strconv.FormatFloat(stat.Mean(x, nil), 'f', 1, 64),
+			strconv.FormatFloat(stat.StdDev(x, nil), 'f', 1, 64),
+			strconv.Itoa(len(x))})
+	}
+	return out.WriteTable(Header, rows)
+}
+
+// DeltaPrices 返回股价序列的涨跌幅(%)序列
+func DeltaPrices(prices []float64) []float64 {
+	if prices == nil || len(prices) < 2 {
+		return nil
+	}
+	xs := make([]float64, 0, len(prices)-1)
+	for i := 1; i < len(prices); i++ {
+		xs = append(xs, (prices[i]-prices[i-1])/prices[i-1]*100.)
+	}
+	return xs
+}
+
+// LoadStockClose根据股票代码载入该股票的收盘价及对应的除权日(见package events)
+// closes[dates[i]]...closes[dates[i+1]之间的权值相同。
+// dates[i]的值是closes的下标
+func LoadStockClose(stockcode string) (closes []float64, dates []int, err error) {
+	frm, err := readr.Load(context.Background(), cfg.DataPath(stockcode), &readr.Options{Header: true, Lenient: true, Adjust: cfg.Adjust})
+	if err != nil {
+		return nil, nil, err
+	}
+	return frm.Closes, events.Segments(events.Detect(frm), frm.Len()), nil
+}
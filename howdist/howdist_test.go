@@ -0,0 +1,23 @@
+package howdist
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDeltaPrices(t *testing.T) {
+	got := DeltaPrices([]float64{10, 11, 9.9, 9.9})
+	want := []float64{10, -10, 0}
+	if len(got) != len(want) {
+		t.Fatalf("DeltaPrices = %v, want %v", got, want)
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("DeltaPrices = %v, want %v", got, want)
+			break
+		}
+	}
+	if got := DeltaPrices([]float64{10}); got != nil {
+		t.Errorf("DeltaPrices of one price = %v, want nil", got)
+	}
+}
@@ -0,0 +1,97 @@
+// package modifystocklist 用来修改原有的stocklist.csv文件。
+// 从各股票的log文件取出最后更新日及权值, 写入股票列表(见package roster)。
+package modifystocklist
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"stockstat/config"
+	"stockstat/readr"
+	"stockstat/roster"
+	"strconv"
+)
+
+//////////////////////////////////////////////////////////
+// Run
+
+// cfg 是运行设置, 见package config
+var cfg = config.Default()
+
+// Run 读取各股票的log文件, 把最后更新日期及权值写入股票列表文件。
+// 任何一只股票出错时不改写股票列表文件。可以对自己的输出再次运行。
+func Run(c *config.Config) error {
+	cfg = c
+	limitedThreads = cfg.Limiter()
+
+	stocks, err := roster.Load(cfg.RosterPath())
+	if err != nil {
+		return err
+	}
+
+	// 逐个读取log文件，解析出"date", "power"字段
+	errs := make(chan error)
+	for i := range stocks {
+		go func(s *roster.Stock) {
+			err := ModifyStockList(s)
+			if err == nil {
+				fmt.Println(s.Name, "modified")
+			}
+			errs <- err
+		}(&stocks[i])
+	}
+	for range stocks {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return roster.Save(cfg.RosterPath(), stocks)
+}
+
+/////////////////////////////////////////////////////////
+// ModifyStockList
+////////////////////////////////////////////////////////
+
+var (
+	limitedThreads = cfg.Limiter()
+)
+
+// ModifyStockList 从股票s的log文件中取出最后更新日期及权值, 记入s
+func ModifyStockList(s *roster.Stock) error {
+	limitedThreads <- struct{}{}
+	defer func() {
+		<-limitedThreads
+	}()
+
+	// 读出股票log, 解析出日期与权值
+	fname := filepath.Join(cfg.DataDir, s.Code.Number+".log")
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	rd := csv.NewReader(f)
+	rd.Comma = ','
+	rd.TrimLeadingSpace = true
+	doc, err := rd.Read()
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", fname, err)
+	}
+	if len(doc) < 8 {
+		return fmt.Errorf("%s: want 8 fields, got %d", fname, len(doc))
+	}
+	date, err := readr.ParseDate(doc[0])
+	if err != nil {
+		return fmt.Errorf("%s: %v", fname, err)
+	}
+	power, err := strconv.ParseFloat(doc[7], 64)
+	if err != nil {
+		return fmt.Errorf("%s: power: %v", fname, err)
+	}
+	s.Updated, s.LastPower = date, power
+	return nil
+}
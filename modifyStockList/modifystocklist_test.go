@@ -0,0 +1,66 @@
+package modifystocklist
+
+import (
+	"os"
+	"path/filepath"
+	"stockstat/config"
+	"strings"
+	"testing"
+)
+
+func TestRunTwice(t *testing.T) {
+	c := config.Default()
+	c.DataDir = t.TempDir()
+	c.Roster = filepath.Join(c.DataDir, "stocklist.csv")
+	files := map[string]string{
+		"stocklist.csv": "600000,浦发银行\r\n000001,平安银行\r\n",
+		"600000.log":    "2018-07-20,10.5,10.8,10.7,10.4,1500,16000,1.5\r\n",
+		"000001.log":    "2018-07-19,9.1,9.3,9.2,9.0,2500,23000,2.25\r\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(c.DataDir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Run(c); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile(c.Roster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "#v2,code,name,exchange,board,updated,power,listed\r\n" +
+		"600000.SH,浦发银行,SH,main,2018-07-20,1.5,\r\n" +
+		"000001.SZ,平安银行,SZ,main,2018-07-19,2.25,\r\n"
+	if string(first) != want {
+		t.Errorf("first run:\n%s\nwant\n%s", first, want)
+	}
+
+	// 对自己的输出再次运行, 结果不变
+	if err := Run(c); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	second, err := os.ReadFile(c.Roster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(second) != string(first) {
+		t.Errorf("second run:\n%s\nwant\n%s", second, first)
+	}
+}
+
+func TestRunKeepsRosterOnError(t *testing.T) {
+	c := config.Default()
+	c.DataDir = t.TempDir()
+	c.Roster = filepath.Join(c.DataDir, "stocklist.csv")
+	old := "600000,浦发银行\r\n000001,平安银行\r\n"
+	os.WriteFile(c.Roster, []byte(old), 0644)
+	os.WriteFile(filepath.Join(c.DataDir, "600000.log"), []byte("2018-07-20,10.5,10.8,10.7,10.4,1500,16000,1.5\r\n"), 0644)
+	err := Run(c) // 000001没有log文件
+	if err == nil || !strings.Contains(err.Error(), "000001.log") {
+		t.Errorf("err = %v, want missing 000001.log", err)
+	}
+	if got, _ := os.ReadFile(c.Roster); string(got) != old {
+		t.Errorf("roster changed to\n%s", got)
+	}
+}
@@ -0,0 +1,239 @@
+// package panel 把多只股票的数据按同一日期索引对齐为 日期 × 股票 的矩阵,
+// 作为全市场横截面统计的基础。
+//
+// 各列(收盘、成交量等)分别保存为行主序的矩阵, 第i行是第i个日期, 第j列是第j只股票。
+// 载入时只保留需要的列, 同时载入的文件数由config.Config.Workers限制。
+package panel
+
+import (
+	"context"
+	"fmt"
+	"stockstat/catalog"
+	"stockstat/config"
+	"stockstat/readr"
+	"stockstat/roster"
+	"stockstat/stockcode"
+	"sync"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Panel 是多只股票按同一日期索引对齐的若干列
+type Panel struct {
+	Codes  []stockcode.Code
+	Dates  []string
+	Times  []time.Time
+	Fields []readr.Column
+	// Skipped 是载入时跳过的股票及原因, 见LoadRoster
+	Skipped map[stockcode.Code]string
+
+	data    map[readr.Column][]float64
+	missing []bool // 与data中的矩阵同样排列, 为true的值是填充的
+}
+
+// Options 是载入Panel的选项
+type Options struct {
+	Fields   []readr.Column // 需要的列, 为空时只取收盘
+	Join     readr.Join     // 合并各股票日期的方式
+	Fill     readr.Fill     // 某只股票缺少的日期如何填充
+	From, To time.Time      // 只保留该范围(含两端)的日期, 零值表示不限
+}
+
+// Load 载入数据目录中codes各股票的数据, 价格按cfg.Adjust复权。任一文件出错时返回错误。
+func Load(ctx context.Context, cfg *config.Config, codes []stockcode.Code, opts *Options) (*Panel, error) {
+	return load(ctx, cfg, codes, opts, false)
+}
+
+// LoadRoster 载入股票列表中的全部股票。数据目录中有清单时跳过没有数据或过时的股票,
+// 无法读取或缺少所需列的股票也被跳过, 均记录在Skipped中。
+func LoadRoster(ctx context.Context, cfg *config.Config, opts *Options) (*Panel, error) {
+	stocks, err := roster.Load(cfg.RosterPath())
+	if err != nil {
+		return nil, err
+	}
+	codes, skipped, err := catalog.Filter(cfg.DataDir, stocks.Codes())
+	if err != nil {
+		return nil, err
+	}
+	p, err := load(ctx, cfg, codes, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	for code, why := range skipped {
+		p.Skipped[code] = why
+	}
+	return p, nil
+}
+
+func load(ctx context.Context, cfg *config.Config, codes []stockcode.Code, opts *Options, lenient bool) (*Panel, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = []readr.Column{readr.ColClose}
+	}
+
+	frames := make([]*readr.Frame, len(codes))
+	errs := make([]error, len(codes))
+	var wg sync.WaitGroup
+	limit := cfg.Limiter()
+	for k, code := range codes {
+		wg.Add(1)
+		go func(k int, code stockcode.Code) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			frames[k], errs[k] = loadFields(ctx, cfg, code, fields, opts)
+		}(k, code)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p := &Panel{Fields: fields, Skipped: make(map[stockcode.Code]string)}
+	var loaded []*readr.Frame
+	for k, code := range codes {
+		if errs[k] != nil {
+			if !lenient {
+				return nil, errs[k]
+			}
+			p.Skipped[code] = errs[k].Error()
+			continue
+		}
+		p.Codes = append(p.Codes, code)
+		loaded = append(loaded, frames[k])
+	}
+	frames = nil
+
+	a, err := readr.Align(loaded, opts.Join, opts.Fill)
+	if err != nil {
+		return nil, err
+	}
+	p.Dates, p.Times = a.Dates, a.Times
+	rows, cols := a.Len(), len(p.Codes)
+	p.data = make(map[readr.Column][]float64, len(fields))
+	for _, f := range fields {
+		m := make([]float64, rows*cols)
+		for j, frm := range a.Frames {
+			for i, v := range *frm.Column(f) {
+				m[i*cols+j] = v
+			}
+		}
+		p.data[f] = m
+	}
+	p.missing = make([]bool, rows*cols)
+	for j, miss := range a.Missing {
+		for i, v := range miss {
+			p.missing[i*cols+j] = v
+		}
+	}
+	return p, nil
+}
+
+// loadFields 读取一只股票的数据, 只复制日期范围内需要的列, 使整个文件的数据可以尽早释放
+func loadFields(ctx context.Context, cfg *config.Config, code stockcode.Code, fields []readr.Column, opts *Options) (*readr.Frame, error) {
+	path := cfg.DataPath(code.String())
+	frm, err := readr.Load(ctx, path, &readr.Options{Header: true, Lenient: true, Adjust: cfg.Adjust})
+	if err != nil {
+		return nil, err
+	}
+	lo, hi := 0, frm.Len()
+	for lo < hi && !opts.From.IsZero() && frm.Times[lo].Before(opts.From) {
+		lo++
+	}
+	for hi > lo && !opts.To.IsZero() && frm.Times[hi-1].After(opts.To) {
+		hi--
+	}
+
+	res := &readr.Frame{
+		Dates:      append([]string(nil), frm.Dates[lo:hi]...),
+		Times:      append([]time.Time(nil), frm.Times[lo:hi]...),
+		Adjustment: frm.Adjustment,
+	}
+	// 按停牌填充时需要收盘
+	need := append([]readr.Column{readr.ColClose}, fields...)
+	for _, f := range need {
+		if !frm.Has(f) {
+			return nil, fmt.Errorf("%s: no %s column", path, f)
+		}
+		*res.Column(f) = append([]float64(nil), (*frm.Column(f))[lo:hi]...)
+	}
+	return res, nil
+}
+
+// Len 返回日期数
+func (p *Panel) Len() int {
+	return len(p.Dates)
+}
+
+// Width 返回股票数
+func (p *Panel) Width() int {
+	return len(p.Codes)
+}
+
+// Has 报告Panel中是否有列f
+func (p *Panel) Has(f readr.Column) bool {
+	_, ok := p.data[f]
+	return ok
+}
+
+// Index 返回股票code在Codes中的位置, 没有时返回-1
+func (p *Panel) Index(code stockcode.Code) int {
+	for j, c := range p.Codes {
+		if c == code {
+			return j
+		}
+	}
+	return -1
+}
+
+// field 返回列f的矩阵, f须在Fields中
+func (p *Panel) field(f readr.Column) []float64 {
+	m, ok := p.data[f]
+	if !ok {
+		panic(fmt.Sprintf("panel: no %s column", f))
+	}
+	return m
+}
+
+// At 返回第j只股票第i个日期的列f
+func (p *Panel) At(f readr.Column, i, j int) float64 {
+	return p.field(f)[i*p.Width()+j]
+}
+
+// Missing 报告第j只股票第i个日期的值是否是填充的
+func (p *Panel) Missing(i, j int) bool {
+	return p.missing[i*p.Width()+j]
+}
+
+// Row 返回第i个日期各股票的列f, 即一个横截面, 与Panel共用存储
+func (p *Panel) Row(f readr.Column, i int) []float64 {
+	n := p.Width()
+	return p.field(f)[i*n : (i+1)*n : (i+1)*n]
+}
+
+// Series 返回第j只股票各日期的列f
+func (p *Panel) Series(f readr.Column, j int) []float64 {
+	m, n := p.field(f), p.Width()
+	s := make([]float64, p.Len())
+	for i := range s {
+		s[i] = m[i*n+j]
+	}
+	return s
+}
+
+// Matrix 返回列f的 日期 × 股票 矩阵, 缺少的值为NaN或按Options.Fill填充。
+// 没有日期或股票时返回的错误满足errors.Is(err, readr.ErrNoData)。
+func (p *Panel) Matrix(f readr.Column) (*mat.Dense, error) {
+	return p.dense(append([]float64(nil), p.field(f)...))
+}
+
+func (p *Panel) dense(data []float64) (*mat.Dense, error) {
+	if p.Len() == 0 || p.Width() == 0 { // mat.NewDense不接受0行或0列
+		return nil, fmt.Errorf("panel: %d dates x %d stocks: %w", p.Len(), p.Width(), readr.ErrNoData)
+	}
+	return mat.NewDense(p.Len(), p.Width(), data), nil
+}
@@ -0,0 +1,43 @@
+package panel
+
+import (
+	"context"
+	"os"
+	"stockstat/config"
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	files := map[string]string{
+		"600000": "#date,open,high,cloe,low,volumn,pow\n" +
+			"2014-03-03,10,11,10,9,100,1\n2014-03-04,10,11,11,9,100,1\n2014-03-05,11,12,12,10,100,1\n",
+		"600036": "#date,open,high,cloe,low,volumn,pow\n" +
+			"2014-03-03,20,21,20,19,100,1\n2014-03-05,20,22,22,19,100,1\n",
+	}
+	for code, data := range files {
+		if err := os.WriteFile(cfg.DataPath(code), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	codes := []stockcode.Code{stockcode.MustParse("600000"), stockcode.MustParse("600036")}
+	p, err := Load(context.Background(), cfg, codes, &Options{Join: readr.JoinOuter, Fill: readr.FillForward})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 3 || p.Width() != 2 {
+		t.Fatalf("panel %d x %d, want 3 x 2", p.Len(), p.Width())
+	}
+	if got := p.Series(readr.ColClose, 1); got[1] != 20 || !p.Missing(1, 1) || p.Missing(1, 0) {
+		t.Errorf("600036 closes %v, missing %v, want forward-filled 20 on 2014-03-04", got, p.Missing(1, 1))
+	}
+	if got := p.Row(readr.ColClose, 2); got[0] != 12 || got[1] != 22 {
+		t.Errorf("row 2014-03-05 = %v, want [12 22]", got)
+	}
+	if _, err := Load(context.Background(), cfg, append(codes, stockcode.MustParse("000001")), nil); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
@@ -0,0 +1,93 @@
+package panel
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/stockcode"
+	"strconv"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// 横截面变换, 见Run
+const (
+	OpNone   = "none"
+	OpRank   = "rank"
+	OpZScore = "zscore"
+	OpDemean = "demean"
+)
+
+// Run 把codes各股票按日期对齐的opts.Fields[0]列写到out, 每只股票一列, 缺失值为空;
+// codes为空时使用股票列表中可用的全部股票。op不是OpNone时写出每天各股票间的横截面变换。
+// 跳过的股票打印到标准错误。
+func Run(c *config.Config, out *cli.Output, codes []string, opts *Options, op string) error {
+	if len(opts.Fields) != 1 {
+		return fmt.Errorf("panel: want one field, got %d", len(opts.Fields))
+	}
+	switch op {
+	case "", OpNone, OpRank, OpZScore, OpDemean:
+	default:
+		return cli.Usagef("unknown op %q, want none, rank, zscore or demean", op)
+	}
+	col := opts.Fields[0]
+
+	var p *Panel
+	var err error
+	ctx := context.Background()
+	if len(codes) == 0 {
+		p, err = LoadRoster(ctx, c, opts)
+	} else {
+		list := make([]stockcode.Code, len(codes))
+		for i, s := range codes {
+			if list[i], err = stockcode.Parse(s); err != nil {
+				return cli.Usagef("%v", err)
+			}
+		}
+		p, err = Load(ctx, c, list, opts)
+	}
+	if err != nil {
+		return err
+	}
+	for code, why := range p.Skipped {
+		fmt.Fprintln(os.Stderr, code, "skipped:", why)
+	}
+
+	at := func(i, j int) float64 { return p.At(col, i, j) }
+	var m *mat.Dense
+	switch op {
+	case OpRank:
+		m, err = p.Rank(col)
+	case OpZScore:
+		m, err = p.ZScore(col)
+	case OpDemean:
+		m, err = p.Demean(col)
+	}
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		at = m.At
+	}
+
+	header := []string{"date"}
+	for _, code := range p.Codes {
+		header = append(header, code.String())
+	}
+	rows := make([][]string, p.Len())
+	for i := range rows {
+		row := []string{p.Dates[i]}
+		for j := 0; j < p.Width(); j++ {
+			if v := at(i, j); math.IsNaN(v) {
+				row = append(row, "")
+			} else {
+				row = append(row, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+		rows[i] = row
+	}
+	return out.WriteTable(header, rows)
+}
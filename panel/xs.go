@@ -0,0 +1,103 @@
+package panel
+
+import (
+	"math"
+	"sort"
+	"stockstat/readr"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// 横截面运算: 对每个日期的各股票值分别变换, 返回 日期 × 股票 矩阵。
+// NaN不参与计算, 结果中仍为NaN。没有日期或股票时返回的错误满足errors.Is(err, readr.ErrNoData)。
+
+// Rank 返回列f在每个日期的排名, 最小的为1, 相同的值取平均排名
+func (p *Panel) Rank(f readr.Column) (*mat.Dense, error) {
+	return p.cross(f, rank)
+}
+
+// ZScore 返回列f在每个日期的标准分数(x-均值)/标准差, 标准差为0时为0
+func (p *Panel) ZScore(f readr.Column) (*mat.Dense, error) {
+	return p.cross(f, zscore)
+}
+
+// Demean 返回列f减去每个日期的均值
+func (p *Panel) Demean(f readr.Column) (*mat.Dense, error) {
+	return p.cross(f, demean)
+}
+
+// cross 对列f的每一行调用fn, fn把src变换后写到dst
+func (p *Panel) cross(f readr.Column, fn func(dst, src []float64)) (*mat.Dense, error) {
+	n := p.Width()
+	data := make([]float64, p.Len()*n)
+	for i := 0; i < p.Len(); i++ {
+		fn(data[i*n:(i+1)*n], p.Row(f, i))
+	}
+	return p.dense(data)
+}
+
+func rank(dst, src []float64) {
+	idx := make([]int, 0, len(src))
+	for j, v := range src {
+		dst[j] = math.NaN()
+		if !math.IsNaN(v) {
+			idx = append(idx, j)
+		}
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return src[idx[a]] < src[idx[b]] })
+	for lo := 0; lo < len(idx); {
+		hi := lo + 1
+		for hi < len(idx) && src[idx[hi]] == src[idx[lo]] {
+			hi++
+		}
+		r := float64(lo+hi+1) / 2 // 第lo+1至第hi名的平均
+		for _, j := range idx[lo:hi] {
+			dst[j] = r
+		}
+		lo = hi
+	}
+}
+
+// meanStd 返回src中非NaN值的均值、样本标准差及个数
+func meanStd(src []float64) (mean, std float64, n int) {
+	for _, v := range src {
+		if !math.IsNaN(v) {
+			mean += v
+			n++
+		}
+	}
+	if n == 0 {
+		return math.NaN(), math.NaN(), 0
+	}
+	mean /= float64(n)
+	if n < 2 {
+		return mean, 0, n
+	}
+	for _, v := range src {
+		if !math.IsNaN(v) {
+			std += (v - mean) * (v - mean)
+		}
+	}
+	return mean, math.Sqrt(std / float64(n-1)), n
+}
+
+func zscore(dst, src []float64) {
+	mean, std, _ := meanStd(src)
+	for j, v := range src {
+		switch {
+		case math.IsNaN(v):
+			dst[j] = v
+		case std == 0:
+			dst[j] = 0
+		default:
+			dst[j] = (v - mean) / std
+		}
+	}
+}
+
+func demean(dst, src []float64) {
+	mean, _, _ := meanStd(src)
+	for j, v := range src {
+		dst[j] = v - mean
+	}
+}
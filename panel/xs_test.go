@@ -0,0 +1,105 @@
+package panel
+
+import (
+	"errors"
+	"math"
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// testPanel 返回收盘为rows的Panel, 每行一个日期
+func testPanel(rows ...[]float64) *Panel {
+	p := &Panel{
+		Codes:  make([]stockcode.Code, len(rows[0])),
+		Dates:  make([]string, len(rows)),
+		Fields: []readr.Column{readr.ColClose},
+		data:   map[readr.Column][]float64{},
+	}
+	for _, r := range rows {
+		p.data[readr.ColClose] = append(p.data[readr.ColClose], r...)
+	}
+	p.missing = make([]bool, len(p.data[readr.ColClose]))
+	return p
+}
+
+func TestCrossSection(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name string
+		row  []float64
+		rank []float64
+		z    []float64
+		dm   []float64
+	}{
+		{"distinct", []float64{3, 1, 2}, []float64{3, 1, 2}, []float64{1, -1, 0}, []float64{1, -1, 0}},
+		{"ties", []float64{5, 1, 5, 3}, []float64{3.5, 1, 3.5, 2}, nil, []float64{1.5, -2.5, 1.5, -0.5}},
+		{"nan", []float64{2, nan, 4}, []float64{1, nan, 2},
+			[]float64{-math.Sqrt2 / 2, nan, math.Sqrt2 / 2}, []float64{-1, nan, 1}},
+		{"constant", []float64{7, 7, 7}, []float64{2, 2, 2}, []float64{0, 0, 0}, []float64{0, 0, 0}},
+		{"single", []float64{nan, 9, nan}, []float64{nan, 1, nan}, []float64{nan, 0, nan}, []float64{nan, 0, nan}},
+	}
+	same := func(x, y float64) bool {
+		return math.IsNaN(x) && math.IsNaN(y) || math.Abs(x-y) < 1e-12
+	}
+	for _, tt := range tests {
+		// 两个日期, 第二个日期是第一个的2倍, 排名不变
+		second := make([]float64, len(tt.row))
+		for j, v := range tt.row {
+			second[j] = 2 * v
+		}
+		p := testPanel(tt.row, second)
+		check := func(what string, m interface{ At(i, j int) float64 }, err error, want []float64) {
+			if err != nil {
+				t.Fatalf("%s: %s: %v", tt.name, what, err)
+			}
+			if want == nil {
+				return
+			}
+			for j, w := range want {
+				if got := m.At(0, j); !same(got, w) {
+					t.Errorf("%s: %s[%d] = %v, want %v", tt.name, what, j, got, w)
+				}
+			}
+		}
+		rank, err := p.Rank(readr.ColClose)
+		check("rank", rank, err, tt.rank)
+		z, err := p.ZScore(readr.ColClose)
+		check("zscore", z, err, tt.z)
+		dm, err := p.Demean(readr.ColClose)
+		check("demean", dm, err, tt.dm)
+		for j, w := range tt.rank {
+			if got := rank.At(1, j); !same(got, w) {
+				t.Errorf("%s: second rank[%d] = %v, want %v", tt.name, j, got, w)
+			}
+		}
+		if !same(p.At(readr.ColClose, 0, 0), tt.row[0]) {
+			t.Errorf("%s: cross-section changed the panel", tt.name)
+		}
+	}
+}
+
+func TestEmptyPanel(t *testing.T) {
+	empty := []*Panel{
+		{Fields: []readr.Column{readr.ColClose}, data: map[readr.Column][]float64{readr.ColClose: nil}},
+		{Dates: []string{"2024-01-02"}, Fields: []readr.Column{readr.ColClose}, data: map[readr.Column][]float64{readr.ColClose: nil}},
+	}
+	for _, p := range empty {
+		for name, fn := range map[string]func(readr.Column) (*mat.Dense, error){
+			"Matrix": p.Matrix, "Rank": p.Rank, "ZScore": p.ZScore, "Demean": p.Demean,
+		} {
+			if m, err := fn(readr.ColClose); m != nil || !errors.Is(err, readr.ErrNoData) {
+				t.Errorf("%d x %d panel: %s = %v, %v, want ErrNoData", p.Len(), p.Width(), name, m, err)
+			}
+		}
+	}
+	m, err := testPanel([]float64{1, 2}).Matrix(readr.ColClose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, c := m.Dims(); r != 1 || c != 2 {
+		t.Errorf("Matrix dims %d x %d, want 1 x 2", r, c)
+	}
+}
@@ -0,0 +1,103 @@
+package readr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Adjustment 是价格的复权方式。
+// 数据文件保存不复权的价格, Power为累计复权因子(上市时为1, 每次除权后增大),
+// 复权只改变开高收低四列, 不改变文件。
+type Adjustment int
+
+const (
+	AdjustNone     Adjustment = iota // 不复权, 即实际成交价
+	AdjustForward                    // 前复权, 最后一天的价格不变: price * pow / 最后的pow
+	AdjustBackward                   // 后复权, 复权因子为1时的价格不变: price * pow
+)
+
+func (a Adjustment) String() string {
+	switch a {
+	case AdjustForward:
+		return "forward"
+	case AdjustBackward:
+		return "backward"
+	}
+	return "none"
+}
+
+// ParseAdjustment 解析复权方式: none, forward(qfq, 前复权), backward(hfq, 后复权)
+func ParseAdjustment(s string) (Adjustment, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none", "raw", "不复权":
+		return AdjustNone, nil
+	case "forward", "qfq", "前复权":
+		return AdjustForward, nil
+	case "backward", "hfq", "后复权":
+		return AdjustBackward, nil
+	}
+	return AdjustNone, fmt.Errorf("unknown adjustment %q, want none, forward or backward", s)
+}
+
+// ErrNoPower 表示需要复权但数据中没有权值列
+var ErrNoPower = errors.New("readr: no pow column to adjust prices")
+
+var errBadPower = errors.New("pow must be positive to adjust prices")
+
+// adjustColumns 是复权时改变的列
+var adjustColumns = []Column{ColOpen, ColHigh, ColClose, ColLow}
+
+// factor 返回权值为power时由不复权价格得到复权价格的乘数, base为前复权的基准权值
+func (a Adjustment) factor(power, base float64) float64 {
+	switch a {
+	case AdjustForward:
+		return power / base
+	case AdjustBackward:
+		return power
+	}
+	return 1
+}
+
+// Adjust 把价格换算为复权方式mode。可以重复调用, 每次都从不复权的价格换算,
+// 不会重复复权。没有权值列时返回ErrNoPower(mode为AdjustNone时除外)。
+// 有不为正(含NaN)的权值时返回错误, frame不变。
+func (frame *Frame) Adjust(mode Adjustment) error {
+	if mode == frame.Adjustment {
+		return nil
+	}
+	if !frame.Has(ColPower) {
+		return ErrNoPower
+	}
+	base := frame.adjBase
+	if mode == AdjustForward {
+		if n := len(frame.Power); n > 0 {
+			base = frame.Power[n-1]
+		}
+		if !(base > 0) {
+			return fmt.Errorf("readr: last pow is %v, can't adjust forward", base)
+		}
+	}
+	// 先检查全部权值, 以免出错时只换算了一部分
+	for i, pow := range frame.Power {
+		if !(pow > 0) {
+			return fmt.Errorf("readr: %s: pow is %v", frame.Dates[i], pow)
+		}
+	}
+	for i, pow := range frame.Power {
+		f := mode.factor(pow, base) / frame.Adjustment.factor(pow, frame.adjBase)
+		for _, c := range adjustColumns {
+			(*frame.Column(c))[i] *= f
+		}
+	}
+	frame.Adjustment, frame.adjBase = mode, base
+	return nil
+}
+
+// adjust 按复权方式a换算一根K线的价格
+func (bar *Bar) adjust(a Adjustment, base float64) {
+	f := a.factor(bar.Power, base)
+	for _, c := range adjustColumns {
+		*bar.field(c) *= f
+	}
+}
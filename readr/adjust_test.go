@@ -0,0 +1,48 @@
+package readr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAdjustBadPowerLeavesFrame(t *testing.T) {
+	for _, bad := range []float64{0, -1, math.NaN()} {
+		frame := testFrame(3)
+		frame.Power[2] = bad
+		want := append([]float64(nil), frame.Closes...)
+		if err := frame.Adjust(AdjustBackward); err == nil {
+			t.Errorf("pow %v: Adjust succeeded", bad)
+		}
+		if frame.Adjustment != AdjustNone {
+			t.Errorf("pow %v: Adjustment = %s, want none", bad, frame.Adjustment)
+		}
+		for i := range want {
+			if frame.Closes[i] != want[i] {
+				t.Errorf("pow %v: close[%d] = %v, want %v unchanged", bad, i, frame.Closes[i], want[i])
+			}
+		}
+	}
+}
+
+func TestAdjust(t *testing.T) {
+	frame := testFrame(2)
+	frame.Power = []float64{1, 2}
+	if err := frame.Adjust(AdjustForward); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Closes[0] != 5 || frame.Closes[1] != 11 {
+		t.Errorf("forward closes = %v, want [5 11]", frame.Closes)
+	}
+	if err := frame.Adjust(AdjustBackward); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Closes[0] != 10 || frame.Closes[1] != 22 {
+		t.Errorf("backward closes = %v, want [10 22]", frame.Closes)
+	}
+	if err := frame.Adjust(AdjustNone); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Closes[0] != 10 || frame.Closes[1] != 11 {
+		t.Errorf("raw closes = %v, want [10 11]", frame.Closes)
+	}
+}
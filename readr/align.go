@@ -0,0 +1,185 @@
+package readr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Join 是Align合并各Frame日期的方式
+type Join int
+
+const (
+	JoinInner Join = iota // 只保留各Frame都有的日期
+	JoinOuter             // 保留任一Frame有的日期
+	JoinLeft              // 保留第一个Frame的日期
+)
+
+func (j Join) String() string {
+	switch j {
+	case JoinOuter:
+		return "outer"
+	case JoinLeft:
+		return "left"
+	}
+	return "inner"
+}
+
+// ParseJoin 解析inner, outer或left
+func ParseJoin(s string) (Join, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "inner":
+		return JoinInner, nil
+	case "outer":
+		return JoinOuter, nil
+	case "left":
+		return JoinLeft, nil
+	}
+	return JoinInner, fmt.Errorf("unknown join %q, want inner, outer or left", s)
+}
+
+// Fill 是对齐后某只股票缺少的日期如何填充
+type Fill int
+
+const (
+	FillNaN       Fill = iota // 各列为NaN
+	FillForward               // 沿用前一行的各列
+	FillSuspended             // 按停牌处理: 开高收低为前一行的收盘, 量额、换手率、涨跌为0
+)
+
+func (f Fill) String() string {
+	switch f {
+	case FillForward:
+		return "forward"
+	case FillSuspended:
+		return "suspended"
+	}
+	return "nan"
+}
+
+// ParseFill 解析nan, forward(ffill)或suspended
+func ParseFill(s string) (Fill, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "nan", "none", "":
+		return FillNaN, nil
+	case "forward", "ffill":
+		return FillForward, nil
+	case "suspended", "suspend":
+		return FillSuspended, nil
+	}
+	return FillNaN, fmt.Errorf("unknown fill %q, want nan, forward or suspended", s)
+}
+
+// Aligned 是按同一日期索引对齐的多个Frame
+type Aligned struct {
+	Dates  []string
+	Times  []time.Time
+	Frames []*Frame // 与Align的参数一一对应, 各Frame的行与Dates一一对应
+	// Missing[k][i]为true表示Frames[k]原来没有第i个日期的数据, 该行是填充的
+	Missing [][]bool
+}
+
+// Len 返回日期数
+func (a *Aligned) Len() int {
+	return len(a.Dates)
+}
+
+// Align 按how合并各Frame的日期, 返回对齐后的各Frame, 参数不变。
+// 某只股票缺少的日期按fill填充; 第一行之前没有可以沿用的数据, 总是填NaN。
+// 各Frame的日期须为升序, 重复的日期只取第一行。
+func Align(frames []*Frame, how Join, fill Fill) (*Aligned, error) {
+	times := make([][]time.Time, len(frames))
+	for k, frame := range frames {
+		t, err := frame.ascendingTimes()
+		if err != nil {
+			return nil, err
+		}
+		times[k] = t
+	}
+
+	a := &Aligned{
+		Times:   alignIndex(times, how),
+		Frames:  make([]*Frame, len(frames)),
+		Missing: make([][]bool, len(frames)),
+	}
+	a.Dates = make([]string, len(a.Times))
+	for i, t := range a.Times {
+		a.Dates[i] = t.Format(DateLayout)
+	}
+	for k, frame := range frames {
+		a.Frames[k], a.Missing[k] = frame.reindex(times[k], a.Times, fill)
+	}
+	return a, nil
+}
+
+// alignIndex 按how合并各日期序列, 返回升序且不重复的日期
+func alignIndex(times [][]time.Time, how Join) []time.Time {
+	if len(times) == 0 {
+		return nil
+	}
+	count := make(map[int64]int) // 各日期的Unix时间 -> 有该日期的序列数
+	for k, list := range times {
+		if how == JoinLeft && k > 0 {
+			break
+		}
+		for i, t := range list {
+			if i == 0 || !t.Equal(list[i-1]) {
+				count[t.Unix()]++
+			}
+		}
+	}
+	var index []time.Time
+	for u, n := range count {
+		if how != JoinInner || n == len(times) {
+			index = append(index, time.Unix(u, 0).UTC())
+		}
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].Before(index[j]) })
+	return index
+}
+
+// reindex 返回按index排列的frame及各行是否为填充的, times为frame各行的日期
+func (frame *Frame) reindex(times, index []time.Time, fill Fill) (*Frame, []bool) {
+	var cols []Column
+	for c := ColOpen; int(c) < len(columnNames); c++ {
+		if frame.Has(c) {
+			cols = append(cols, c)
+		}
+	}
+	res := NewFrame(cols, len(index))
+	res.Adjustment, res.adjBase = frame.Adjustment, frame.adjBase
+	missing := make([]bool, len(index))
+
+	j, last := 0, -1 // last为t之前最后一个日期的第一行
+	for i, t := range index {
+		for j < len(times) && times[j].Before(t) {
+			if j == 0 || !times[j].Equal(times[j-1]) {
+				last = j
+			}
+			j++
+		}
+		var bar Bar
+		switch {
+		case j < len(times) && times[j].Equal(t):
+			bar = frame.Bar(j)
+		case last < 0 || fill == FillNaN:
+			missing[i] = true
+			for _, c := range cols {
+				*bar.field(c) = math.NaN()
+			}
+		default:
+			missing[i] = true
+			bar = frame.Bar(last)
+			if fill == FillSuspended {
+				bar.Open, bar.High, bar.Low = bar.Close, bar.Close, bar.Close
+				bar.Volumn, bar.Amount, bar.Turnover = 0, 0, 0
+				bar.Change, bar.PctChange = 0, 0
+			}
+		}
+		bar.Date, bar.Time = index[i].Format(DateLayout), index[i]
+		res.Append(&bar)
+	}
+	return res, missing
+}
@@ -0,0 +1,93 @@
+package readr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAlign(t *testing.T) {
+	a := klineFrame([]kline{
+		{"2014-03-03", 10, 11, 10, 9, 100, 1},
+		{"2014-03-04", 10, 11, 10.5, 9, 100, 1},
+		{"2014-03-04", 99, 99, 99, 99, 99, 1}, // 重复的日期只取第一行
+		{"2014-03-06", 10.5, 11, 11, 10, 100, 1},
+	})
+	b := klineFrame([]kline{
+		{"2014-03-04", 20, 21, 20, 19, 200, 2},
+		{"2014-03-05", 20, 22, 21, 19, 300, 2},
+		{"2014-03-07", 21, 23, 22, 20, 400, 2},
+	})
+	nan := math.NaN()
+	tests := []struct {
+		how     Join
+		fill    Fill
+		dates   []string
+		closesA []float64
+		closesB []float64
+		missB   []bool
+		volumeB []float64
+	}{
+		{JoinInner, FillNaN, []string{"2014-03-04"},
+			[]float64{10.5}, []float64{20}, []bool{false}, []float64{200}},
+		{JoinLeft, FillNaN, []string{"2014-03-03", "2014-03-04", "2014-03-06"},
+			[]float64{10, 10.5, 11}, []float64{nan, 20, nan}, []bool{true, false, true}, []float64{nan, 200, nan}},
+		{JoinLeft, FillForward, []string{"2014-03-03", "2014-03-04", "2014-03-06"},
+			[]float64{10, 10.5, 11}, []float64{nan, 20, 21}, []bool{true, false, true}, []float64{nan, 200, 300}},
+		{JoinOuter, FillForward, []string{"2014-03-03", "2014-03-04", "2014-03-05", "2014-03-06", "2014-03-07"},
+			[]float64{10, 10.5, 10.5, 11, 11}, []float64{nan, 20, 21, 21, 22},
+			[]bool{true, false, false, true, false}, []float64{nan, 200, 300, 300, 400}},
+		{JoinOuter, FillSuspended, []string{"2014-03-03", "2014-03-04", "2014-03-05", "2014-03-06", "2014-03-07"},
+			[]float64{10, 10.5, 10.5, 11, 11}, []float64{nan, 20, 21, 21, 22},
+			[]bool{true, false, false, true, false}, []float64{nan, 200, 300, 0, 400}},
+	}
+	same := func(x, y float64) bool { return x == y || math.IsNaN(x) && math.IsNaN(y) }
+	for _, tt := range tests {
+		name := tt.how.String() + "/" + tt.fill.String()
+		al, err := Align([]*Frame{a, b}, tt.how, tt.fill)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(al.Dates) != len(tt.dates) {
+			t.Fatalf("%s: dates %v, want %v", name, al.Dates, tt.dates)
+		}
+		fa, fb := al.Frames[0], al.Frames[1]
+		for i, d := range tt.dates {
+			if al.Dates[i] != d || fa.Dates[i] != d || fb.Dates[i] != d {
+				t.Errorf("%s: row %d date %s/%s/%s, want %s", name, i, al.Dates[i], fa.Dates[i], fb.Dates[i], d)
+			}
+			if !same(fa.Closes[i], tt.closesA[i]) || !same(fb.Closes[i], tt.closesB[i]) {
+				t.Errorf("%s: %s closes %v, %v, want %v, %v", name, d, fa.Closes[i], fb.Closes[i], tt.closesA[i], tt.closesB[i])
+			}
+			if al.Missing[1][i] != tt.missB[i] || !same(fb.Volumns[i], tt.volumeB[i]) {
+				t.Errorf("%s: %s missing %v, volume %v, want %v, %v", name, d, al.Missing[1][i], fb.Volumns[i], tt.missB[i], tt.volumeB[i])
+			}
+			if tt.fill == FillSuspended && al.Missing[1][i] && !math.IsNaN(fb.Closes[i]) {
+				if fb.Opens[i] != fb.Closes[i] || fb.Highs[i] != fb.Closes[i] || fb.Lows[i] != fb.Closes[i] {
+					t.Errorf("%s: %s suspended bar %v", name, d, fb.Bar(i))
+				}
+			}
+		}
+	}
+	if a.Len() != 4 || b.Len() != 3 {
+		t.Errorf("Align changed its arguments")
+	}
+}
+
+func TestParseJoinFill(t *testing.T) {
+	for _, j := range []Join{JoinInner, JoinOuter, JoinLeft} {
+		if got, err := ParseJoin(j.String()); err != nil || got != j {
+			t.Errorf("ParseJoin(%q) = %v, %v", j, got, err)
+		}
+	}
+	for _, f := range []Fill{FillNaN, FillForward, FillSuspended} {
+		if got, err := ParseFill(f.String()); err != nil || got != f {
+			t.Errorf("ParseFill(%q) = %v, %v", f, got, err)
+		}
+	}
+	if _, err := ParseJoin("cross"); err == nil {
+		t.Error("ParseJoin(cross) succeeded")
+	}
+	if _, err := ParseFill("zero"); err == nil {
+		t.Error("ParseFill(zero) succeeded")
+	}
+}
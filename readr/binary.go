@@ -0,0 +1,320 @@
+package readr
+
+import (
+	"bufio"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"stockstat/safefile"
+	"strings"
+	"time"
+)
+
+// 二进制列存格式(小端):
+//
+//	magic   [4]byte  "STKB"
+//	version uint16
+//	flags   uint16   binaryFlate: 数据区用flate压缩
+//	ncols   uint16
+//	cols    [ncols]uint8  各列的Column值, 不含ColDate
+//	keylen  uint16        (版本2起) 生成缓存时的读取选项, 见cacheKey
+//	key     [keylen]byte
+//	rows    uint32
+//	数据区: dates [rows]int32 (自1970-01-01起的天数), 随后按cols顺序每列[rows]float64
+//
+// Column的值写入了文件, 因此只能在末尾增加新的Column。
+const (
+	binaryMagic   = "STKB"
+	binaryVersion = 2
+	binaryFlate   = 1 << 0
+
+	// BinaryExt 是二进制缓存文件的扩展名
+	BinaryExt = ".stkb"
+)
+
+// ErrBadBinary 表示二进制文件格式不对或版本不支持
+var ErrBadBinary = errors.New("readr: bad binary file")
+
+// ErrTooManyRows 表示Frame超过二进制文件的行数上限, 无法写出
+var ErrTooManyRows = errors.New("readr: too many rows for binary file")
+
+// errStaleCache 表示缓存是用不同的读取选项生成的
+var errStaleCache = errors.New("readr: cache built with other options")
+
+// maxBinaryRows 是二进制文件的最大行数, 每年约250个交易日, 足够400年。
+// 读取时先检查行数, 以免损坏的文件使ReadBinary分配过多内存; 写出时拒绝超过上限的Frame。
+const maxBinaryRows = 100000
+
+type binaryHeader struct {
+	Magic   [4]byte
+	Version uint16
+	Flags   uint16
+	NCols   uint16
+}
+
+// WriteBinary 以二进制列存格式写出frame, compress为true时压缩数据区。
+// 行数超过上限时返回ErrTooManyRows, 不写出任何内容。
+func (frame *Frame) WriteBinary(w io.Writer, compress bool) error {
+	return frame.writeBinary(w, compress, "")
+}
+
+func (frame *Frame) writeBinary(w io.Writer, compress bool, key string) error {
+	if frame.Len() > maxBinaryRows {
+		return fmt.Errorf("%w: %d rows, limit %d", ErrTooManyRows, frame.Len(), maxBinaryRows)
+	}
+	var cols []Column
+	for c := ColOpen; int(c) < len(columnNames); c++ {
+		if frame.Has(c) {
+			cols = append(cols, c)
+		}
+	}
+	hdr := binaryHeader{Version: binaryVersion, NCols: uint16(len(cols))}
+	copy(hdr.Magic[:], binaryMagic)
+	if compress {
+		hdr.Flags |= binaryFlate
+	}
+	bw := bufio.NewWriter(w)
+	binary.Write(bw, binary.LittleEndian, &hdr)
+	for _, c := range cols {
+		bw.WriteByte(byte(c))
+	}
+	binary.Write(bw, binary.LittleEndian, uint16(len(key)))
+	bw.WriteString(key)
+	binary.Write(bw, binary.LittleEndian, uint32(frame.Len()))
+
+	var body io.Writer = bw
+	var zw *flate.Writer
+	if compress {
+		zw, _ = flate.NewWriter(bw, flate.DefaultCompression)
+		body = zw
+	}
+	days := make([]int32, frame.Len())
+	for i := range days {
+		t := frame.time(i)
+		days[i] = int32(t.Unix() / 86400)
+	}
+	if err := binary.Write(body, binary.LittleEndian, days); err != nil {
+		return err
+	}
+	for _, c := range cols {
+		if err := binary.Write(body, binary.LittleEndian, *frame.Column(c)); err != nil {
+			return err
+		}
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadBinary 读取WriteBinary写出的数据, 也能读取版本1的文件
+func ReadBinary(r io.Reader) (*Frame, error) {
+	frame, _, err := readBinary(r)
+	return frame, err
+}
+
+// readBinary 读取二进制数据, 并返回文件中记录的读取选项
+func readBinary(r io.Reader) (*Frame, string, error) {
+	br := bufio.NewReader(r)
+	var hdr binaryHeader
+	if err := binary.Read(br, binary.LittleEndian, &hdr); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrBadBinary, err)
+	}
+	if string(hdr.Magic[:]) != binaryMagic {
+		return nil, "", fmt.Errorf("%w: magic %q", ErrBadBinary, hdr.Magic[:])
+	}
+	if hdr.Version < 1 || hdr.Version > binaryVersion {
+		return nil, "", fmt.Errorf("%w: version %d", ErrBadBinary, hdr.Version)
+	}
+	cols := make([]Column, hdr.NCols)
+	for i := range cols {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrBadBinary, err)
+		}
+		cols[i] = Column(b)
+		if cols[i] <= ColDate || int(cols[i]) >= len(columnNames) {
+			return nil, "", fmt.Errorf("%w: unknown column %d", ErrBadBinary, b)
+		}
+	}
+	var key []byte
+	if hdr.Version >= 2 {
+		var n uint16
+		if err := binary.Read(br, binary.LittleEndian, &n); err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrBadBinary, err)
+		}
+		key = make([]byte, n)
+		if _, err := io.ReadFull(br, key); err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrBadBinary, err)
+		}
+	}
+	var rows uint32
+	if err := binary.Read(br, binary.LittleEndian, &rows); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrBadBinary, err)
+	}
+	if rows > maxBinaryRows {
+		return nil, "", fmt.Errorf("%w: %d rows", ErrBadBinary, rows)
+	}
+	// 没有压缩时数据区的大小是确定的, 可以先与文件大小比较
+	if f, ok := r.(interface{ Stat() (os.FileInfo, error) }); ok && hdr.Flags&binaryFlate == 0 {
+		need := int64(binary.Size(hdr)) + int64(len(cols)) + 4 + int64(rows)*int64(4+8*len(cols))
+		if hdr.Version >= 2 {
+			need += 2 + int64(len(key))
+		}
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() && fi.Size() < need {
+			return nil, "", fmt.Errorf("%w: %d rows need %d bytes, file has %d", ErrBadBinary, rows, need, fi.Size())
+		}
+	}
+
+	var body io.Reader = br
+	if hdr.Flags&binaryFlate != 0 {
+		zr := flate.NewReader(br)
+		defer zr.Close()
+		body = zr
+	}
+	days := make([]int32, rows)
+	if err := binary.Read(body, binary.LittleEndian, days); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrBadBinary, err)
+	}
+	frame := NewFrame(cols, int(rows))
+	for _, d := range days {
+		t := time.Unix(int64(d)*86400, 0).UTC()
+		frame.Dates = append(frame.Dates, t.Format(DateLayout))
+		frame.Times = append(frame.Times, t)
+	}
+	for _, c := range cols {
+		col := frame.Column(c)
+		*col = (*col)[:rows]
+		if err := binary.Read(body, binary.LittleEndian, *col); err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrBadBinary, err)
+		}
+	}
+	return frame, string(key), nil
+}
+
+// time 返回第i行的日期, Times缺失时由Dates解析
+func (frame *Frame) time(i int) time.Time {
+	if i < len(frame.Times) {
+		return frame.Times[i]
+	}
+	t, _ := ParseDate(frame.Dates[i])
+	return t
+}
+
+// CachePath 返回数据文件path对应的二进制缓存文件, 如600000.csv对应600000.stkb
+func CachePath(path string) string {
+	if i := strings.LastIndexByte(path, '.'); i > strings.LastIndexAny(path, `/\`) {
+		path = path[:i]
+	}
+	return path + BinaryExt
+}
+
+// cacheFresh 报告path的二进制缓存是否存在且不比path旧
+func cacheFresh(path string) bool {
+	src, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	bin, err := os.Stat(CachePath(path))
+	if err != nil {
+		return false
+	}
+	return !bin.ModTime().Before(src.ModTime())
+}
+
+// cacheKey 返回影响解析结果的读取选项, 记录在缓存中。
+// 用不同选项读取时不使用缓存, 以免得到按其他布局解析的数据。
+func cacheKey(opts *Options) string {
+	return fmt.Sprintf("format=%v header=%t schema=%s", opts.Format, opts.Header, opts.Schema)
+}
+
+// loadCache 读取path的二进制缓存, 缓存的读取选项与opts不同时返回errStaleCache
+func loadCache(path string, opts *Options) (*Frame, error) {
+	f, err := os.Open(CachePath(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	frame, key, err := readBinary(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name(), err)
+	}
+	if key != cacheKey(opts) {
+		return nil, fmt.Errorf("%s: %w", f.Name(), errStaleCache)
+	}
+	return frame, nil
+}
+
+// UpdateCache 在缓存不存在, 比数据文件旧或读取选项不同时, 以严格模式读取path并写出二进制缓存。
+// 返回是否重写了缓存。有坏行的文件不生成缓存, 以免掩盖错误;
+// 超过行数上限的文件也不生成缓存, 返回ErrTooManyRows。
+func UpdateCache(ctx context.Context, path string, opts *Options, compress bool) (bool, error) {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if cacheFresh(path) {
+		if _, err := loadCache(path, &o); err == nil {
+			return false, nil
+		}
+	}
+	o.Lenient, o.NoCache, o.Adjust = false, true, AdjustNone
+	o.From, o.To = time.Time{}, time.Time{}
+	frame, err := Load(ctx, path, &o)
+	if err != nil {
+		return false, err
+	}
+	if frame.Len() > maxBinaryRows {
+		return false, fmt.Errorf("%s: %w: %d rows, limit %d", path, ErrTooManyRows, frame.Len(), maxBinaryRows)
+	}
+	f, err := safefile.Create(CachePath(path))
+	if err != nil {
+		return false, err
+	}
+	defer f.Abort()
+	f.NoBackup = true // 缓存可以随时重新生成
+	if err := frame.writeBinary(f, compress, cacheKey(&o)); err != nil {
+		return false, err
+	}
+	if err := f.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// frameSource 逐行读出内存中的Frame, 用于从二进制缓存读取
+type frameSource struct {
+	frame *Frame
+	cols  []Column
+	i     int
+}
+
+func newFrameSource(frame *Frame) *frameSource {
+	src := &frameSource{frame: frame}
+	for c := ColOpen; int(c) < len(columnNames); c++ {
+		if frame.Has(c) {
+			src.cols = append(src.cols, c)
+		}
+	}
+	src.cols = append([]Column{ColDate}, src.cols...)
+	return src
+}
+
+func (src *frameSource) columns() []Column {
+	return src.cols
+}
+
+func (src *frameSource) read(bar *Bar) (int, error) {
+	if src.i >= src.frame.Len() {
+		return 0, io.EOF
+	}
+	*bar = src.frame.Bar(src.i)
+	src.i++
+	return src.i, nil
+}
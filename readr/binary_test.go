@@ -0,0 +1,151 @@
+package readr
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testFrame 返回有n行, 带权值的Frame
+func testFrame(n int) *Frame {
+	frame := NewFrame([]Column{ColOpen, ColHigh, ColClose, ColLow, ColVolumn, ColPower}, n)
+	t0 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		t := t0.AddDate(0, 0, i)
+		x := 10 + float64(i)
+		frame.Append(&Bar{Date: t.Format(DateLayout), Time: t, Open: x, High: x + 1, Close: x, Low: x - 1, Volumn: 100, Power: 1})
+	}
+	return frame
+}
+
+func TestReadBinaryRowLimit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testFrame(3).WriteBinary(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	ncols := int(binary.LittleEndian.Uint16(data[8:10]))
+	rowsAt := 10 + ncols
+
+	huge := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(huge[rowsAt:], 1<<31)
+	if _, err := ReadBinary(bytes.NewReader(huge)); !errors.Is(err, ErrBadBinary) {
+		t.Errorf("rows = 1<<31: err = %v, want ErrBadBinary", err)
+	}
+
+	// 行数在上限以内但文件被截断: 不分配即报错
+	big := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(big[rowsAt:], maxBinaryRows)
+	path := filepath.Join(t.TempDir(), "600000"+BinaryExt)
+	if err := os.WriteFile(path, big, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := ReadBinary(f); !errors.Is(err, ErrBadBinary) {
+		t.Errorf("truncated file: err = %v, want ErrBadBinary", err)
+	}
+
+	frame, err := ReadBinary(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Len() != 3 {
+		t.Errorf("Len = %d, want 3", frame.Len())
+	}
+}
+
+func TestWriteBinaryRowLimit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testFrame(maxBinaryRows+1).WriteBinary(&buf, true); !errors.Is(err, ErrTooManyRows) {
+		t.Errorf("err = %v, want ErrTooManyRows", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %d bytes", buf.Len())
+	}
+
+	path := filepath.Join(t.TempDir(), "600000.csv")
+	writeCSVFile(t, path, testFrame(maxBinaryRows+1))
+	if ok, err := UpdateCache(context.Background(), path, &Options{Header: true}, false); ok || !errors.Is(err, ErrTooManyRows) {
+		t.Errorf("UpdateCache = %v, %v, want ErrTooManyRows", ok, err)
+	}
+	if _, err := os.Stat(CachePath(path)); !os.IsNotExist(err) {
+		t.Errorf("cache written: %v", err)
+	}
+}
+
+// writeCSVFile 按数据目录的格式写出frame
+func writeCSVFile(t *testing.T, path string, frame *Frame) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := frame.WriteCSV(f, &StockDataFormat); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "600000.csv")
+	writeCSVFile(t, path, testFrame(5))
+	opts := &Options{Header: true}
+	if ok, err := UpdateCache(ctx, path, opts, true); !ok || err != nil {
+		t.Fatalf("UpdateCache = %v, %v", ok, err)
+	}
+	if ok, err := UpdateCache(ctx, path, opts, true); ok || err != nil {
+		t.Errorf("second UpdateCache = %v, %v, want fresh cache kept", ok, err)
+	}
+	frame, err := Load(ctx, path, opts)
+	if err != nil || frame.Len() != 5 || frame.Closes[4] != 14 {
+		t.Fatalf("Load from cache = %v, %v", frame, err)
+	}
+
+	// 缓存只用于生成它时的选项
+	other := &Options{Header: true, Format: FormatCSV}
+	f, err := os.Create(CachePath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = testFrame(2).writeBinary(f, false, cacheKey(other))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame, err := Load(ctx, path, other); err != nil || frame.Len() != 2 {
+		t.Errorf("Load with cached options = %v, %v, want the 2 cached rows", frame, err)
+	}
+	if frame, err := Load(ctx, path, opts); err != nil || frame.Len() != 5 {
+		t.Errorf("Load with other options = %v, %v, want the 5 rows of the text", frame, err)
+	}
+	if ok, err := UpdateCache(ctx, path, opts, false); !ok || err != nil {
+		t.Errorf("UpdateCache with other options = %v, %v, want rewritten", ok, err)
+	}
+
+	// 损坏的缓存报告错误, 而不是默默改读文本
+	if err := os.WriteFile(CachePath(path), []byte("STKB\x09\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(ctx, path, opts); !errors.Is(err, ErrBadBinary) {
+		t.Errorf("Load with bad cache: err = %v, want ErrBadBinary", err)
+	}
+	if _, err := Load(ctx, path, &Options{Header: true, NoCache: true}); err != nil {
+		t.Errorf("Load with NoCache: %v", err)
+	}
+	if ok, err := UpdateCache(ctx, path, opts, false); !ok || err != nil {
+		t.Errorf("UpdateCache over bad cache = %v, %v, want rewritten", ok, err)
+	}
+	if _, err := Load(ctx, path, opts); err != nil {
+		t.Errorf("Load after rebuild: %v", err)
+	}
+}
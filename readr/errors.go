@@ -0,0 +1,73 @@
+package readr
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrNotFound 表示数据文件不存在
+	ErrNotFound = errors.New("readr: file not found")
+	// ErrNoData 表示数据文件中没有任何数据行
+	ErrNoData = errors.New("readr: no data")
+)
+
+// SchemaError 表示文件的列布局不符合要求, 如缺少必需的列
+type SchemaError struct {
+	File string
+	Err  error
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e *SchemaError) Unwrap() error { return e.Err }
+
+// ParseError 记录无法解析的数据及其位置。
+// Line为文件中的行号(JSON文件为记录序号), Column为列号, 均从1开始, 0表示未知。
+type ParseError struct {
+	File   string
+	Line   int
+	Column int
+	Field  string // 出错的列名, 可能为空
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%s:%d:%d: %s: %v", e.File, e.Line, e.Column, e.Field, e.Err)
+	}
+	return fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Warnings 收集宽松模式下被跳过的行, 可并发使用。
+// 用法: var ws Warnings; Load(ctx, path, &Options{Lenient: true, Warn: ws.Add})
+type Warnings struct {
+	mu   sync.Mutex
+	list []*ParseError
+}
+
+// Add 记录一条告警
+func (ws *Warnings) Add(e *ParseError) {
+	ws.mu.Lock()
+	ws.list = append(ws.list, e)
+	ws.mu.Unlock()
+}
+
+// List 返回已记录的告警
+func (ws *Warnings) List() []*ParseError {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return append([]*ParseError(nil), ws.list...)
+}
+
+// Len 返回已记录的告警数
+func (ws *Warnings) Len() int {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return len(ws.list)
+}
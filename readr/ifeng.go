@@ -0,0 +1,275 @@
+package readr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// IfengRecord 是凤凰网日K线JSON文件中的一行:
+// [date, open, high, close, low, volume, change, pct, ma5, ma10, ma20, vma5, vma10, vma20, turnover]
+type IfengRecord struct {
+	Date               string
+	Open, High         float64
+	Close, Low         float64
+	Volumn             float64
+	Change, PctChange  float64
+	MA5, MA10, MA20    float64
+	VMA5, VMA10, VMA20 float64
+	Turnover           float64
+}
+
+// ifengMinFields 是一行记录至少应有的字段数(date至volume)
+const ifengMinFields = 6
+
+// ScanIfengJSON 逐行解析凤凰网日K线JSON, 每解析出一行即调用fn,
+// 不会把整个文件读入内存。fn返回错误时停止解析并返回该错误。
+func ScanIfengJSON(r io.Reader, fn func(rec *IfengRecord) error) error {
+	src := newIfengSource(r, "")
+	for {
+		line, row, err := src.nextRow()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rec, col, err := parseIfengRow(row)
+		if err != nil {
+			return fmt.Errorf("record %d field %d: %v", line, col+1, err)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadIfengJSON 读取凤凰网日K线JSON文件(如stat/6.txt)为Frame, 出错时打印错误并返回nil。
+// 需要区分错误类型时请使用Load。
+func ReadIfengJSON(fname string) *Frame {
+	frame, err := Load(context.Background(), fname, &Options{Format: FormatIfeng})
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	return frame
+}
+
+// ifengSource 逐个解码{"record":[...]}中"record"数组的元素。
+// 若"record"之前有"columns":[...](见Frame.WriteJSON), 则按其中的列名解析各元素,
+// 否则按凤凰网日K线的字段顺序解析。
+type ifengSource struct {
+	dec    *json.Decoder
+	file   string
+	line   int     // 已解码的元素个数
+	schema *Schema // 由"columns"得到的布局, 没有时为nil
+	state  int
+	from   string // 早于此日期的元素不解析, 见before
+}
+
+const (
+	ifengStart  = iota // 尚未读到'{'
+	ifengKeys          // 在对象中, 寻找"record"
+	ifengRecord        // 在"record"数组中
+	ifengDone
+)
+
+func newIfengSource(r io.Reader, file string) *ifengSource {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return &ifengSource{dec: dec, file: file}
+}
+
+func (src *ifengSource) columns() []Column {
+	if src.schema != nil {
+		return src.schema.Columns
+	}
+	return ifengColumns
+}
+
+// seek 前进到"record"数组的下一个元素之前, 没有更多元素时返回io.EOF
+func (src *ifengSource) seek() error {
+	dec := src.dec
+	for {
+		switch src.state {
+		case ifengStart:
+			if err := expectDelim(dec, '{'); err != nil {
+				return err
+			}
+			src.state = ifengKeys
+		case ifengKeys:
+			if !dec.More() {
+				if err := expectDelim(dec, '}'); err != nil {
+					return err
+				}
+				src.state = ifengDone
+				continue
+			}
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			switch key, _ := tok.(string); key {
+			case "record":
+				if err := expectDelim(dec, '['); err != nil {
+					return err
+				}
+				src.state = ifengRecord
+			case "columns":
+				var names []string
+				if err := dec.Decode(&names); err != nil {
+					return err
+				}
+				if src.schema, err = DetectSchema(names); err != nil {
+					return &SchemaError{src.file, err}
+				}
+			default:
+				var skip json.RawMessage
+				if err := dec.Decode(&skip); err != nil {
+					return err
+				}
+			}
+		case ifengRecord:
+			if dec.More() {
+				return nil
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return err
+			}
+			src.state = ifengKeys
+		default:
+			return io.EOF
+		}
+	}
+}
+
+// skip 报告日期为date的元素是否在From之前
+func (src *ifengSource) skip(date interface{}) bool {
+	s, ok := date.(string)
+	return ok && before(s, src.from)
+}
+
+// nextRow 返回下一个元素及其序号(从1开始), 没有更多元素时返回io.EOF
+func (src *ifengSource) nextRow() (int, []interface{}, error) {
+	if err := src.seek(); err != nil {
+		return 0, nil, err
+	}
+	src.line++
+	var row []interface{}
+	if err := src.dec.Decode(&row); err != nil {
+		return src.line, nil, fmt.Errorf("record %d: %v", src.line, err)
+	}
+	return src.line, row, nil
+}
+
+// read 实现rowSource。JSON语法错误无法恢复, 返回普通错误; 数值错误返回*ParseError。
+func (src *ifengSource) read(bar *Bar) (int, error) {
+	line, row, err := src.nextRow()
+	for err == nil && len(row) > 0 && src.skip(row[0]) {
+		line, row, err = src.nextRow()
+	}
+	if err != nil {
+		return line, err
+	}
+	if src.schema != nil {
+		record := make([]string, len(row))
+		for i, v := range row {
+			switch x := v.(type) {
+			case json.Number:
+				record[i] = x.String()
+			case string:
+				record[i] = x
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		return line, parseErr(src.file, line, src.schema, record, bar)
+	}
+	*bar = Bar{}
+	rec, col, err := parseIfengRow(row)
+	if err == nil {
+		bar.Time, err = ParseDate(rec.Date)
+	}
+	if err != nil {
+		e := &ParseError{File: src.file, Line: line, Column: col + 1, Err: err}
+		if col < len(ifengColumns) {
+			e.Field = ifengColumns[col].String()
+		}
+		return line, e
+	}
+	bar.Date = bar.Time.Format(DateLayout)
+	bar.Open, bar.High, bar.Close, bar.Low = rec.Open, rec.High, rec.Close, rec.Low
+	bar.Volumn = rec.Volumn
+	bar.Change, bar.PctChange = rec.Change, rec.PctChange
+	bar.MA5, bar.MA10, bar.MA20 = rec.MA5, rec.MA10, rec.MA20
+	bar.VMA5, bar.VMA10, bar.VMA20 = rec.VMA5, rec.VMA10, rec.VMA20
+	bar.Turnover = rec.Turnover
+	return line, nil
+}
+
+// parseIfengRow 解析一行记录, 出错时返回出错的字段序号(从0开始)
+func parseIfengRow(row []interface{}) (*IfengRecord, int, error) {
+	if len(row) < ifengMinFields {
+		return nil, len(row), fmt.Errorf("want at least %d fields, got %d", ifengMinFields, len(row))
+	}
+	rec := IfengRecord{}
+	date, ok := row[0].(string)
+	if !ok {
+		return nil, 0, fmt.Errorf("date %v is not a string", row[0])
+	}
+	rec.Date = date
+	vs := []*float64{
+		&rec.Open, &rec.High, &rec.Close, &rec.Low, &rec.Volumn,
+		&rec.Change, &rec.PctChange,
+		&rec.MA5, &rec.MA10, &rec.MA20,
+		&rec.VMA5, &rec.VMA10, &rec.VMA20,
+		&rec.Turnover,
+	}
+	for i, v := range row[1:] {
+		if i >= len(vs) {
+			break
+		}
+		x, err := ifengNumber(v)
+		if err != nil {
+			return nil, i + 1, err
+		}
+		*vs[i] = x
+	}
+	return &rec, 0, nil
+}
+
+// ifengNumber 解析JSON中的数值, 数值可能是数字, 也可能是带千分位的字符串, 如"573,336.19"
+func ifengNumber(v interface{}) (float64, error) {
+	switch x := v.(type) {
+	case json.Number:
+		return x.Float64()
+	case string:
+		return ParseNumber(x)
+	case nil:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("unexpected value %v", v)
+}
+
+// ParseNumber 解析可能带千分位分隔符的数字, 如"573,336.19"
+func ParseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if strings.IndexByte(s, ',') >= 0 {
+		s = strings.Replace(s, ",", "", -1)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expect %q, got %v", want, tok)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package readr
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// sample 是仓库中的凤凰网日K线样例
+const sample = "../stat/6.txt"
+
+func TestReadIfengJSONSample(t *testing.T) {
+	frame := ReadIfengJSON(sample)
+	if frame == nil {
+		t.Fatal("ReadIfengJSON returned nil")
+	}
+	if frame.Len() != 700 {
+		t.Errorf("Len = %d, want 700", frame.Len())
+	}
+	tests := []struct {
+		i    int
+		want Bar
+	}{
+		{0, Bar{Date: "2014-01-28", Open: 9.12, High: 9.29, Close: 9.19, Low: 9.12, Volumn: 573336.19,
+			Change: 0.07, PctChange: 0.77, MA5: 9.19, MA10: 9.19, MA20: 9.19,
+			VMA5: 573336.19, VMA10: 573336.19, VMA20: 573336.19, Turnover: 0.38}},
+		{699, Bar{Date: "2017-01-26", Open: 16.69, High: 16.84, Close: 16.74, Low: 16.61, Volumn: 86029.07,
+			Change: 0.05, PctChange: 0.3, MA5: 16.658, MA10: 16.554, MA20: 16.38,
+			VMA5: 127555.65, VMA10: 172331.34, VMA20: 163184.45, Turnover: 0.04}},
+	}
+	for _, tt := range tests {
+		got := frame.Bar(tt.i)
+		tt.want.Time = day(tt.want.Date)
+		if got != tt.want {
+			t.Errorf("bar %d = %+v\nwant %+v", tt.i, got, tt.want)
+		}
+	}
+}
+
+func TestScanIfengJSONSample(t *testing.T) {
+	f, err := os.Open(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var first, last IfengRecord
+	n := 0
+	err = ScanIfengJSON(f, func(rec *IfengRecord) error {
+		if n == 0 {
+			first = *rec
+		}
+		last = *rec
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 700 || first.Date != "2014-01-28" || first.VMA20 != 573336.19 || last.Date != "2017-01-26" || last.VMA10 != 172331.34 {
+		t.Errorf("%d records, first %+v, last %+v", n, first, last)
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"573,336.19", 573336.19},
+		{"1,234,567", 1234567},
+		{" 9.120 ", 9.12},
+		{"-0.120", -0.12},
+	}
+	for _, tt := range tests {
+		if got, err := ParseNumber(tt.s); err != nil || got != tt.want {
+			t.Errorf("ParseNumber(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+	if _, err := ParseNumber("abc"); err == nil {
+		t.Error(`ParseNumber("abc") succeeded`)
+	}
+	err := ScanIfengJSON(strings.NewReader(`{"record":[["2014-01-28","9.1","x","9.1","9.1","1"]]}`), func(*IfengRecord) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "record 1 field 3") {
+		t.Errorf("bad field: err = %v", err)
+	}
+}
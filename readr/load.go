@@ -0,0 +1,108 @@
+package readr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format 是数据文件的格式
+type Format int
+
+const (
+	FormatAuto  Format = iota // 根据扩展名及文件内容判断
+	FormatCSV                 // 逗号分隔
+	FormatTable               // 空白分隔, 见stat/doc.md
+	FormatIfeng               // 凤凰网日K线JSON
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatCSV:
+		return "csv"
+	case FormatTable:
+		return "table"
+	case FormatIfeng:
+		return "ifeng"
+	}
+	return "auto"
+}
+
+// Options 控制Load及Scanner的行为, 零值表示: 自动判断格式, 无表头, 严格模式, 读取全部日期
+type Options struct {
+	Format Format
+	// Header 为true时第一行是表头, 按表头识别各列, 无法识别时返回*SchemaError
+	Header bool
+	// Schema 是没有表头时使用的布局名, 为空时csv用SchemaStockData, table用SchemaTable
+	Schema string
+	// Lenient 为true时跳过无法解析的行, 并通过Warn报告;
+	// 否则遇到第一个错误即返回*ParseError
+	Lenient bool
+	// Warn 在宽松模式下接收被跳过的行, 可以为nil
+	Warn func(*ParseError)
+	// From, To 限定读取的日期范围(含两端), 零值表示不限。
+	// From之前的行只比较日期前缀, 不解析, 其中的坏行也不报告。
+	From, To time.Time
+	// NoCache 为true时不使用二进制缓存(见UpdateCache), 总是解析文本
+	NoCache bool
+	// Adjust 是读取时对价格的复权方式(见Adjustment), 需要权值列。
+	// 前复权以整个文件最后一天的权值为基准, 与From/To无关。
+	Adjust Adjustment
+}
+
+// ctxCheckRows 每读这么多行检查一次ctx是否已取消
+const ctxCheckRows = 1024
+
+// Load 读取数据文件path为Frame。
+// 文件不存在时返回的错误满足errors.Is(err, ErrNotFound);
+// 列布局不对时返回*SchemaError; 数据无法解析时返回*ParseError。
+func Load(ctx context.Context, path string, opts *Options) (*Frame, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	s, err := OpenScanner(ctx, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	frame := NewFrame(s.Columns(), 0)
+	frame.Adjustment, frame.adjBase = opts.Adjust, s.adjBase
+	for s.Scan() {
+		frame.Append(s.Bar())
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if frame.Len() == 0 {
+		return nil, fmt.Errorf("%s: %w", path, ErrNoData)
+	}
+	return frame, nil
+}
+
+// detectFormat 以'{'开头的是JSON, 扩展名为.csv或首行含逗号的是csv, 其余为table
+func detectFormat(path string, rd *bufio.Reader) Format {
+	head, _ := rd.Peek(512)
+	head = bytes.TrimLeft(head, " \t\r\n\ufeff")
+	if len(head) > 0 && head[0] == '{' {
+		return FormatIfeng
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return FormatCSV
+	}
+	if isMarker(string(head)) { // 跳过标记行
+		if i := bytes.IndexByte(head, '\n'); i >= 0 {
+			head = head[i+1:]
+		}
+	}
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	if bytes.IndexByte(head, ',') >= 0 {
+		return FormatCSV
+	}
+	return FormatTable
+}
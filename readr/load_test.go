@@ -0,0 +1,127 @@
+package readr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const loadData = "date,open,high,close,low,volume,pow\n" +
+	"2024-01-02,10,10.5,10.2,9.8,1000,1\n" +
+	"2024-01-03,10.2,abc,10.4,10.1,1200,1\n" +
+	"2024-01-04,10.4,10.9,10.8,10.3,1500,1\n"
+
+// writeFile 在临时目录中写出数据文件name, 返回路径
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadErrors(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if _, err := Load(ctx, filepath.Join(dir, "600000.csv"), nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing file: err = %v, want ErrNotFound", err)
+	}
+
+	empty := writeFile(t, "600000.csv", "date,open,high,close,low,volume,pow\n")
+	if _, err := Load(ctx, empty, &Options{Header: true}); !errors.Is(err, ErrNoData) {
+		t.Errorf("header only: err = %v, want ErrNoData", err)
+	}
+
+	bad := writeFile(t, "600000.csv", "date,open,high,low,volume\n2024-01-02,10,10.5,9.8,1000\n")
+	var serr *SchemaError
+	if _, err := Load(ctx, bad, &Options{Header: true}); !errors.As(err, &serr) || serr.File != bad {
+		t.Errorf("header without close: err = %v, want *SchemaError", err)
+	}
+	if _, err := Load(ctx, bad, &Options{Schema: "nosuch"}); !errors.As(err, &serr) {
+		t.Errorf("unknown schema: err = %v, want *SchemaError", err)
+	}
+}
+
+func TestLoadStrictLenient(t *testing.T) {
+	ctx := context.Background()
+	path := writeFile(t, "600000.csv", loadData)
+	_, err := Load(ctx, path, &Options{Header: true})
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("strict: err = %v, want *ParseError", err)
+	}
+	if perr.File != path || perr.Line != 3 || perr.Column != 3 || perr.Field != "high" {
+		t.Errorf("ParseError = %+v, want line 3 column 3 field high", perr)
+	}
+	if want := fmt.Sprintf("%s:3:3: high: ", path); !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("error %q, want prefix %q", err, want)
+	}
+
+	var ws Warnings
+	frame, err := Load(ctx, path, &Options{Header: true, Lenient: true, Warn: ws.Add})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Len() != 2 || frame.Dates[0] != "2024-01-02" || frame.Dates[1] != "2024-01-04" {
+		t.Errorf("lenient dates %v, want the bad row skipped", frame.Dates)
+	}
+	if list := ws.List(); ws.Len() != 1 || list[0].Line != 3 || list[0].Column != 3 {
+		t.Errorf("warnings %v, want line 3 column 3", list)
+	}
+}
+
+// rowReader 无限地产生数据行, 读出first字节后调用cancel
+type rowReader struct {
+	n, first int
+	cancel   func()
+	buf      []byte
+}
+
+func (r *rowReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.n > 10*ctxCheckRows*ctxCheckRows {
+			return 0, io.EOF // 没有检查ctx时避免死循环
+		}
+		r.buf = []byte("2024-01-02,10,10.5,10.2,9.8,1000,1\n")
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	r.n += n
+	if r.n >= r.first && r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	return n, nil
+}
+
+func TestLoadCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	path := writeFile(t, "600000.csv", loadData)
+	cancel()
+	if _, err := Load(ctx, path, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled before Load: err = %v, want context.Canceled", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	s, err := NewScanner(ctx, &rowReader{first: 100, cancel: cancel}, "600000.csv", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := 0
+	for s.Scan() {
+		rows++
+	}
+	if !errors.Is(s.Err(), context.Canceled) {
+		t.Errorf("cancelled while reading: err = %v, want context.Canceled", s.Err())
+	}
+	if rows >= 2*ctxCheckRows {
+		t.Errorf("read %d rows after cancel", rows)
+	}
+}
@@ -0,0 +1,121 @@
+package readr
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MarkerVersion 是Marker的当前版本
+const MarkerVersion = 1
+
+// markerPrefix 是标记行的开头, csv及table文件的标记行为"#stockstat v1 adjust=none",
+// JSON文件为{"stockstat":"v1 adjust=none",...}
+const markerPrefix = "#stockstat"
+
+// Marker 是stockstat写出的数据文件中的标记, 说明文件已由本程序转换过及价格的复权方式。
+// 用WriteOptions.Marker写出, 读取时被跳过, 用ReadMarker检查。
+type Marker struct {
+	Version int
+	Adjust  Adjustment
+}
+
+func (m *Marker) String() string {
+	return fmt.Sprintf("v%d adjust=%s", m.Version, m.Adjust)
+}
+
+// ReadMarker 返回文件path中的标记, 文件没有标记时返回nil, nil
+func ReadMarker(path string) (*Marker, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+		}
+		return nil, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if line == "" && err != nil {
+		return nil, nil
+	}
+	m, ok, err := parseMarker(line)
+	if !ok {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// isMarker 报告一行文本是否为标记行
+func isMarker(line string) bool {
+	return strings.HasPrefix(strings.TrimLeft(line, " \t\ufeff"), markerPrefix)
+}
+
+// parseMarker 解析标记行, 不是标记行时ok为false
+func parseMarker(line string) (m *Marker, ok bool, err error) {
+	line = strings.TrimLeft(line, " \t\ufeff")
+	switch {
+	case strings.HasPrefix(line, markerPrefix):
+		line = line[len(markerPrefix):]
+	case strings.HasPrefix(line, `{"stockstat":"`):
+		line = line[len(`{"stockstat":"`):]
+		if i := strings.IndexByte(line, '"'); i >= 0 {
+			line = line[:i]
+		}
+	default:
+		return nil, false, nil
+	}
+	m = &Marker{}
+	for _, field := range strings.Fields(line) {
+		switch {
+		case strings.HasPrefix(field, "v"):
+			if m.Version, err = strconv.Atoi(field[1:]); err != nil {
+				return nil, true, fmt.Errorf("bad marker version %q", field)
+			}
+		case strings.HasPrefix(field, "adjust="):
+			if m.Adjust, err = ParseAdjustment(field[len("adjust="):]); err != nil {
+				return nil, true, err
+			}
+		}
+	}
+	if m.Version <= 0 {
+		return nil, true, fmt.Errorf("marker without version")
+	}
+	return m, true, nil
+}
+
+// marker 返回frame写出时的标记
+func (frame *Frame) marker() *Marker {
+	return &Marker{Version: MarkerVersion, Adjust: frame.Adjustment}
+}
+
+// IsLegacy 报告文件path是否为旧版sina2ifeng换算过而没有标记的数据文件。
+// 旧版写出的已是不复权的价格, 表头为"#date,open,high,cloe,low,volumn,pow", 即SchemaStockData的布局;
+// 新浪下载的后复权数据有成交额, 共8列。有标记的文件返回false, 见ReadMarker。
+func IsLegacy(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, fmt.Errorf("%s: %w", path, ErrNotFound)
+		}
+		return false, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if line == "" && err != nil {
+		return false, nil
+	}
+	line = strings.TrimSpace(strings.TrimLeft(line, " \t\ufeff"))
+	if !strings.HasPrefix(line, "#") || isMarker(line) {
+		return false, nil
+	}
+	s, err := DetectSchema(strings.Split(line, ","))
+	if err != nil {
+		return false, nil
+	}
+	return s.Name == SchemaStockData, nil
+}
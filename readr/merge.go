@@ -0,0 +1,296 @@
+package readr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Policy 是Merge遇到冲突, 即同一日期同一列的值不同时的处理方式
+type Policy int
+
+const (
+	PolicyReplace Policy = iota // 采用新数据的值, 如行情源修订了收盘价
+	PolicyKeep                  // 保留原有的值
+	PolicyReject                // 不合并, 返回ErrConflict
+)
+
+func (p Policy) String() string {
+	switch p {
+	case PolicyKeep:
+		return "keep"
+	case PolicyReject:
+		return "reject"
+	}
+	return "replace"
+}
+
+// ParsePolicy 解析replace(new), keep(old)或reject(fail)
+func ParsePolicy(s string) (Policy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "replace", "new":
+		return PolicyReplace, nil
+	case "keep", "old":
+		return PolicyKeep, nil
+	case "reject", "fail":
+		return PolicyReject, nil
+	}
+	return PolicyReplace, fmt.Errorf("unknown conflict policy %q, want replace, keep or reject", s)
+}
+
+// ErrConflict 表示按PolicyReject合并时有冲突
+var ErrConflict = errors.New("readr: conflicting values")
+
+// Conflict 是同一日期同一列原有的值Old与新的值New不同。
+// 权值的冲突指该日新旧权值之比与最后一个共同日期的比值不同, 即两者除权的日期或比例不同;
+// 各共同日期的比值都相同时只是复权基准不同, 不算冲突, 见MergeReport.PowerRatio。
+type Conflict struct {
+	Date     string
+	Column   Column
+	Old, New float64
+}
+
+// MergeReport 是Merge的结果
+type MergeReport struct {
+	Added     []string   // 原来没有的日期, 升序
+	Changed   int        // 原有的行中值有改变的行数, 只比较原来有的列
+	Conflicts []Conflict // 按日期排序
+	// PowerRatio 是最后一个共同日期新旧权值之比, 没有共同日期或任一方没有权值时为1
+	PowerRatio float64
+}
+
+// 比较时允许的相对误差。权值通常只有三四位小数, 其比值的误差较大。
+const (
+	valueTolerance = 1e-6
+	powerTolerance = 5e-4
+)
+
+// Merge 按日期合并原有的数据old与新数据add, 返回合并后的Frame, old及add不变。
+// old为nil时视为没有数据。两者都须是不复权的数据, 日期须为升序, 重复的日期只取第一行。
+//
+// 只有一方有的日期直接采用。双方都有的日期逐列比较, 不同的值为冲突, 按policy取舍;
+// 一方没有的列或为NaN的值取另一方的。合并后的列为双方的列之并, 一方没有的列在其独有的行中为NaN,
+// 但权值例外: 没有权值的一方沿用前一行(没有前一行时为后一行)的权值。
+//
+// 双方都有权值时, 以被采用的一方(PolicyReplace时为add, 否则为old)的复权基准为准,
+// 另一方独有的行的权值乘以最近的共同日期(优先取之前的)上两者之比, 使整个序列一致。
+// 例如行情源更换了复权基准时, PolicyReplace按新的基准重新换算全部历史。
+//
+// policy为PolicyReject且有冲突时返回nil及包装了ErrConflict的错误, 此时MergeReport仍列出各冲突。
+func Merge(old, add *Frame, policy Policy) (*Frame, *MergeReport, error) {
+	if old == nil {
+		old = &Frame{}
+	}
+	if old.Adjustment != AdjustNone || add.Adjustment != AdjustNone {
+		return nil, nil, errors.New("readr: cannot merge adjusted prices")
+	}
+	rows, err := mergeIndex(old, add)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cols []Column
+	for c := ColOpen; int(c) < len(columnNames); c++ {
+		if old.Has(c) || add.Has(c) {
+			cols = append(cols, c)
+		}
+	}
+
+	// 各共同日期上新旧权值之比, 不是共同日期或权值无效时为NaN
+	report := &MergeReport{PowerRatio: 1}
+	bothPower := old.Has(ColPower) && add.Has(ColPower)
+	ratios := make([]float64, len(rows))
+	for i, r := range rows {
+		ratios[i] = math.NaN()
+		if bothPower && r.old >= 0 && r.add >= 0 {
+			if p, q := old.Power[r.old], add.Power[r.add]; p > 0 && q > 0 {
+				ratios[i] = snapRatio(q / p)
+				report.PowerRatio = ratios[i]
+			}
+		}
+	}
+
+	for i, r := range rows {
+		if r.old < 0 || r.add < 0 {
+			continue
+		}
+		for _, c := range cols {
+			if c == ColPower || !old.Has(c) || !add.Has(c) {
+				continue
+			}
+			x, y := (*old.Column(c))[r.old], (*add.Column(c))[r.add]
+			if differ(x, y, valueTolerance) {
+				report.Conflicts = append(report.Conflicts, Conflict{old.Dates[r.old], c, x, y})
+			}
+		}
+		if differ(ratios[i], report.PowerRatio, powerTolerance) {
+			report.Conflicts = append(report.Conflicts, Conflict{old.Dates[r.old], ColPower, old.Power[r.old], add.Power[r.add]})
+		}
+	}
+	if policy == PolicyReject && len(report.Conflicts) > 0 {
+		c := report.Conflicts[0]
+		return nil, report, fmt.Errorf("%w (%d), first on %s %s: old %v, new %v",
+			ErrConflict, len(report.Conflicts), c.Date, c.Column, c.Old, c.New)
+	}
+
+	frame := mergeRows(old, add, rows, cols, ratios, policy)
+	for i, r := range rows {
+		if r.old < 0 {
+			report.Added = append(report.Added, frame.Dates[i])
+			continue
+		}
+		for _, c := range cols {
+			if old.Has(c) && differ((*old.Column(c))[r.old], (*frame.Column(c))[i], 0) {
+				report.Changed++
+				break
+			}
+		}
+	}
+	return frame, report, nil
+}
+
+// mergeRow 是合并后的一行在old及add中的行号, -1表示没有
+type mergeRow struct {
+	old, add int
+}
+
+// mergeIndex 按日期合并old及add的行
+func mergeIndex(old, add *Frame) ([]mergeRow, error) {
+	ot, err := old.ascendingTimes()
+	if err != nil {
+		return nil, err
+	}
+	at, err := add.ascendingTimes()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]mergeRow, 0, len(ot)+len(at))
+	i, j := 0, 0
+	for i < len(ot) || j < len(at) {
+		if i > 0 && i < len(ot) && ot[i].Equal(ot[i-1]) {
+			i++
+			continue
+		}
+		if j > 0 && j < len(at) && at[j].Equal(at[j-1]) {
+			j++
+			continue
+		}
+		switch {
+		case j == len(at) || i < len(ot) && ot[i].Before(at[j]):
+			rows = append(rows, mergeRow{i, -1})
+			i++
+		case i == len(ot) || at[j].Before(ot[i]):
+			rows = append(rows, mergeRow{-1, j})
+			j++
+		default:
+			rows = append(rows, mergeRow{i, j})
+			i++
+			j++
+		}
+	}
+	return rows, nil
+}
+
+// mergeRows 按rows生成合并后的Frame, 冲突时按policy取舍, 权值的换算见Merge
+func mergeRows(old, add *Frame, rows []mergeRow, cols []Column, ratios []float64, policy Policy) *Frame {
+	// win为冲突时采用的一方, scale把另一方的权值换算到win的基准
+	win, lose := add, old
+	scale := func(ratio float64) float64 { return ratio }
+	if policy != PolicyReplace {
+		win, lose = old, add
+		scale = func(ratio float64) float64 { return 1 / ratio }
+	}
+	index := func(f *Frame, r mergeRow) int {
+		if f == old {
+			return r.old
+		}
+		return r.add
+	}
+
+	// 各行之前(含)及之后(含)最近的共同日期上的比值
+	before := make([]float64, len(rows))
+	after := make([]float64, len(rows))
+	prev, next := math.NaN(), math.NaN()
+	for i := range rows {
+		if !math.IsNaN(ratios[i]) {
+			prev = ratios[i]
+		}
+		before[i] = prev
+	}
+	for i := len(rows) - 1; i >= 0; i-- {
+		if !math.IsNaN(ratios[i]) {
+			next = ratios[i]
+		}
+		after[i] = next
+	}
+
+	frame := NewFrame(cols, len(rows))
+	for i, r := range rows {
+		var bar Bar
+		for _, c := range cols {
+			*bar.field(c) = math.NaN()
+		}
+		for _, f := range []*Frame{lose, win} {
+			k := index(f, r)
+			if k < 0 {
+				continue
+			}
+			bar.Date, bar.Time = f.Dates[k], f.time(k)
+			for _, c := range cols {
+				if f.Has(c) && !math.IsNaN((*f.Column(c))[k]) {
+					*bar.field(c) = (*f.Column(c))[k]
+				}
+			}
+		}
+		if k := index(win, r); k < 0 && win.Has(ColPower) && lose.Has(ColPower) {
+			ratio := before[i]
+			if math.IsNaN(ratio) {
+				ratio = after[i]
+			}
+			if !math.IsNaN(ratio) {
+				bar.Power = lose.Power[index(lose, r)] * scale(ratio)
+			}
+		}
+		frame.Append(&bar)
+	}
+	if frame.Has(ColPower) {
+		fillPower(frame.Power)
+	}
+	return frame
+}
+
+// fillPower 把为NaN的权值换为前一个(没有时为后一个)有效的权值
+func fillPower(power []float64) {
+	last := math.NaN()
+	for i, p := range power {
+		if math.IsNaN(p) {
+			power[i] = last
+		} else {
+			last = p
+		}
+	}
+	last = math.NaN()
+	for i := len(power) - 1; i >= 0; i-- {
+		if math.IsNaN(power[i]) {
+			power[i] = last
+		} else {
+			last = power[i]
+		}
+	}
+}
+
+// snapRatio 把在误差范围内等于1的比值取为1, 以免权值的舍入误差被当作复权基准的变化
+func snapRatio(r float64) float64 {
+	if !differ(r, 1, powerTolerance) {
+		return 1
+	}
+	return r
+}
+
+// differ 报告x与y的相对误差是否超过tol, NaN与任何值都不算不同
+func differ(x, y, tol float64) bool {
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return false
+	}
+	return math.Abs(x-y) > tol*math.Max(math.Abs(x), math.Abs(y))
+}
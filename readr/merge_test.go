@@ -0,0 +1,141 @@
+package readr
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	old := []kline{
+		{"2024-01-02", 10, 11, 10.5, 9.5, 100, 1},
+		{"2024-01-03", 10.5, 11, 10.8, 10, 100, 1},
+		{"2024-01-04", 10.8, 11.5, 11, 10.5, 100, 1},
+	}
+	same := []kline{ // 与old重叠两天, 值相同
+		{"2024-01-03", 10.5, 11, 10.8, 10, 100, 1},
+		{"2024-01-04", 10.8, 11.5, 11, 10.5, 100, 1},
+		{"2024-01-05", 11, 11.2, 11.1, 10.9, 200, 1},
+	}
+	revised := []kline{ // 行情源修订了1月4日的收盘
+		{"2024-01-03", 10.5, 11, 10.8, 10, 100, 1},
+		{"2024-01-04", 10.8, 11.5, 11.05, 10.5, 100, 1},
+		{"2024-01-05", 11, 11.2, 11.1, 10.9, 200, 1},
+	}
+	rebased := []kline{ // 复权基准是old的2倍, 1月5日除权(10送1)
+		{"2024-01-03", 10.5, 11, 10.8, 10, 100, 2},
+		{"2024-01-04", 10.8, 11.5, 11, 10.5, 100, 2},
+		{"2024-01-05", 10, 10.2, 10.1, 9.9, 200, 2.2},
+	}
+	shifted := []kline{ // 与old的除权日不同: 1月3日的权值之比为1, 1月4日为1.1
+		{"2024-01-03", 10.5, 11, 10.8, 10, 100, 1},
+		{"2024-01-04", 10.8, 11.5, 11, 10.5, 100, 1.1},
+	}
+	tests := []struct {
+		name      string
+		add       []kline
+		policy    Policy
+		closes    []float64
+		powers    []float64
+		added     int
+		changed   int
+		conflicts []Column
+		ratio     float64
+	}{
+		{"equal/replace", same, PolicyReplace, []float64{10.5, 10.8, 11, 11.1}, []float64{1, 1, 1, 1}, 1, 0, nil, 1},
+		{"equal/keep", same, PolicyKeep, []float64{10.5, 10.8, 11, 11.1}, []float64{1, 1, 1, 1}, 1, 0, nil, 1},
+		{"equal/reject", same, PolicyReject, []float64{10.5, 10.8, 11, 11.1}, []float64{1, 1, 1, 1}, 1, 0, nil, 1},
+		{"conflict/replace", revised, PolicyReplace, []float64{10.5, 10.8, 11.05, 11.1}, []float64{1, 1, 1, 1}, 1, 1, []Column{ColClose}, 1},
+		{"conflict/keep", revised, PolicyKeep, []float64{10.5, 10.8, 11, 11.1}, []float64{1, 1, 1, 1}, 1, 0, []Column{ColClose}, 1},
+		{"conflict/reject", revised, PolicyReject, nil, nil, 0, 0, []Column{ColClose}, 1},
+		// 新的复权基准被采用时, 按共同日期的比值换算原有的权值; 保留原有的基准时换算新增的行
+		{"rebase/replace", rebased, PolicyReplace, []float64{10.5, 10.8, 11, 10.1}, []float64{2, 2, 2, 2.2}, 1, 3, nil, 2},
+		{"rebase/keep", rebased, PolicyKeep, []float64{10.5, 10.8, 11, 10.1}, []float64{1, 1, 1, 1.1}, 1, 0, nil, 2},
+		{"rebase/reject", rebased, PolicyReject, []float64{10.5, 10.8, 11, 10.1}, []float64{1, 1, 1, 1.1}, 1, 0, nil, 2},
+		{"power conflict/keep", shifted, PolicyKeep, []float64{10.5, 10.8, 11}, []float64{1, 1, 1}, 0, 0, []Column{ColPower}, 1.1},
+		{"power conflict/reject", shifted, PolicyReject, nil, nil, 0, 0, []Column{ColPower}, 1.1},
+	}
+	for _, tt := range tests {
+		o := klineFrame(old)
+		frm, report, err := Merge(o, klineFrame(tt.add), tt.policy)
+		if report == nil {
+			t.Fatalf("%s: no report, err %v", tt.name, err)
+		}
+		if len(report.Conflicts) != len(tt.conflicts) {
+			t.Errorf("%s: conflicts %+v, want %v", tt.name, report.Conflicts, tt.conflicts)
+		} else {
+			for i, c := range report.Conflicts {
+				if c.Column != tt.conflicts[i] {
+					t.Errorf("%s: conflict %d on %s, want %s", tt.name, i, c.Column, tt.conflicts[i])
+				}
+			}
+		}
+		if math.Abs(report.PowerRatio-tt.ratio) > 1e-9 {
+			t.Errorf("%s: PowerRatio %v, want %v", tt.name, report.PowerRatio, tt.ratio)
+		}
+		if tt.closes == nil {
+			if !errors.Is(err, ErrConflict) || frm != nil {
+				t.Errorf("%s: frame %v, err %v, want ErrConflict", tt.name, frm, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(report.Added) != tt.added || report.Changed != tt.changed {
+			t.Errorf("%s: added %v, changed %d, want %d, %d", tt.name, report.Added, report.Changed, tt.added, tt.changed)
+		}
+		if frm.Len() != len(tt.closes) {
+			t.Fatalf("%s: %d rows, want %d", tt.name, frm.Len(), len(tt.closes))
+		}
+		for i := range tt.closes {
+			if math.Abs(frm.Closes[i]-tt.closes[i]) > 1e-9 || math.Abs(frm.Power[i]-tt.powers[i]) > 1e-9 {
+				t.Errorf("%s: %s close %v pow %v, want %v, %v", tt.name, frm.Dates[i], frm.Closes[i], frm.Power[i], tt.closes[i], tt.powers[i])
+			}
+		}
+		checkKlines(t, tt.name+" old", o, old)
+	}
+}
+
+func TestMergeWithoutPower(t *testing.T) {
+	old := klineFrame([]kline{
+		{"2024-01-02", 10, 11, 10.5, 9.5, 100, 1.5},
+		{"2024-01-03", 10.5, 11, 10.8, 10, 100, 1.5},
+	})
+	// 凤凰网没有权值, 新增的行沿用最后的权值
+	add := NewFrame([]Column{ColOpen, ColHigh, ColClose, ColLow, ColVolumn}, 2)
+	for _, d := range []string{"2024-01-03", "2024-01-04"} {
+		tm, _ := ParseDate(d)
+		add.Append(&Bar{Date: d, Time: tm, Open: 10.5, High: 11, Close: 10.8, Low: 10, Volumn: 100})
+	}
+	frm, report, err := Merge(old, add, PolicyReject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Added) != 1 || report.Changed != 0 || report.PowerRatio != 1 {
+		t.Errorf("report %+v, want one added row", report)
+	}
+	if frm.Len() != 3 || frm.Power[2] != 1.5 {
+		t.Errorf("pow %v, want 1.5 carried forward", frm.Power)
+	}
+
+	frm, report, err = Merge(nil, add, PolicyReject)
+	if err != nil || frm.Len() != 2 || len(report.Added) != 2 || frm.Has(ColPower) {
+		t.Errorf("merge into nothing: %v rows, report %+v, err %v", frm.Len(), report, err)
+	}
+	old.Adjustment = AdjustBackward
+	if _, _, err := Merge(old, add, PolicyReplace); err == nil {
+		t.Error("merging adjusted prices succeeded")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, p := range []Policy{PolicyReplace, PolicyKeep, PolicyReject} {
+		if got, err := ParsePolicy(p.String()); err != nil || got != p {
+			t.Errorf("ParsePolicy(%q) = %v, %v", p, got, err)
+		}
+	}
+	if _, err := ParsePolicy("merge"); err == nil {
+		t.Error("ParsePolicy(merge) succeeded")
+	}
+}
@@ -2,96 +2,173 @@
 package readr
 
 import (
-	"bufio"
-	"encoding/csv"
+	"context"
+	"errors"
 	"fmt"
-	"os"
-	"strconv"
-	"strings"
+	"time"
 )
 
+// DateLayout 是数据文件中日期的格式
+const DateLayout = "2006-01-02"
+
+// Frame 按列保存一只股票的行情。
+// Dates, Times及开高收低量总是存在; 其余各列是可选的,
+// 数据文件中没有的列为nil(而不是全0), 用Has判断。
 type Frame struct {
 	Dates   []string
+	Times   []time.Time // 由Dates解析得到
 	Opens   []float64
 	Highs   []float64
 	Closes  []float64
 	Lows    []float64
 	Volumns []float64
-	Power   []float64
+	Amounts []float64 // 成交额
+	Power   []float64 // 权值, 没有权值的数据视为未除权
+
+	// Adjustment 是价格列当前的复权方式, 见Adjust
+	Adjustment Adjustment
+	adjBase    float64 // 前复权的基准权值
+
+	// 以下字段目前仅凤凰网JSON数据提供
+	Changes    []float64 // 涨跌额
+	PctChanges []float64 // 涨跌幅(%)
+	MA5        []float64
+	MA10       []float64
+	MA20       []float64
+	VMA5       []float64 // 成交量均线
+	VMA10      []float64
+	VMA20      []float64
+	Turnovers  []float64 // 换手率(%)
 }
 
-func ReadCSV(fname string, head bool) *Frame {
-	f, err := os.Open(fname)
-	if err != nil {
-		fmt.Println(err)
-		return nil
+// Column 返回列c对应的字段, Frame中没有该字段时返回nil
+func (frame *Frame) Column(c Column) *[]float64 {
+	switch c {
+	case ColOpen:
+		return &frame.Opens
+	case ColHigh:
+		return &frame.Highs
+	case ColClose:
+		return &frame.Closes
+	case ColLow:
+		return &frame.Lows
+	case ColVolumn:
+		return &frame.Volumns
+	case ColAmount:
+		return &frame.Amounts
+	case ColPower:
+		return &frame.Power
+	case ColChange:
+		return &frame.Changes
+	case ColPctChange:
+		return &frame.PctChanges
+	case ColTurnover:
+		return &frame.Turnovers
+	case ColMA5:
+		return &frame.MA5
+	case ColMA10:
+		return &frame.MA10
+	case ColMA20:
+		return &frame.MA20
+	case ColVMA5:
+		return &frame.VMA5
+	case ColVMA10:
+		return &frame.VMA10
+	case ColVMA20:
+		return &frame.VMA20
 	}
-	defer f.Close()
-	reader := csv.NewReader(f)
-	all, err := reader.ReadAll()
-	if err != nil {
-		fmt.Println(err)
-		return nil
-	}
-	if len(all) == 0 {
-		return nil
+	return nil
+}
+
+// Len 返回行数
+func (frame *Frame) Len() int {
+	return len(frame.Dates)
+}
+
+// Has 报告frame中是否有列c
+func (frame *Frame) Has(c Column) bool {
+	if c == ColDate {
+		return frame.Dates != nil
 	}
-	if head && len(all) == 1 {
-		return nil
+	col := frame.Column(c)
+	return col != nil && *col != nil
+}
+
+// ParseDate 解析数据文件中的日期, 接受2006-01-02, 2006/01/02及20060102
+func ParseDate(s string) (time.Time, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err == nil {
+		return t, nil
 	}
-	if head {
-		all = all[1:]
+	for _, layout := range []string{"2006/01/02", "20060102"} {
+		if t, e := time.Parse(layout, s); e == nil {
+			return t, nil
+		}
 	}
+	return t, err
+}
 
-	size := len(all)
+// NewFrame 按列cols分配一个容量为size的空Frame, cols中没有的可选列为nil
+func NewFrame(cols []Column, size int) *Frame {
 	frame := Frame{
-		Dates:   make([]string, size, size),
-		Opens:   make([]float64, size, size),
-		Highs:   make([]float64, size, size),
-		Lows:    make([]float64, size, size),
-		Closes:  make([]float64, size, size),
-		Volumns: make([]float64, size, size),
-		Power:   make([]float64, size, size),
+		Dates: make([]string, 0, size),
+		Times: make([]time.Time, 0, size),
 	}
-	for i, record := range all {
-		frame.Dates[i] = record[0]
-		frame.Opens[i], _ = strconv.ParseFloat(record[1], 64)
-		frame.Highs[i], _ = strconv.ParseFloat(record[2], 64)
-		frame.Lows[i], _ = strconv.ParseFloat(record[3], 64)
-		frame.Closes[i], _ = strconv.ParseFloat(record[4], 64)
-		frame.Volumns[i], _ = strconv.ParseFloat(record[5], 64)
-		frame.Power[i], _ = strconv.ParseFloat(record[6], 64)
+	for _, c := range cols {
+		if col := frame.Column(c); col != nil {
+			*col = make([]float64, 0, size)
+		}
 	}
 	return &frame
 }
 
-func ReadTable(fname string, head bool) *Frame {
-	f, err := os.Open(fname)
-	if err != nil {
-		fmt.Println(err)
-		return nil
+// Append 在frame末尾追加一行, bar中frame没有的列被忽略
+func (frame *Frame) Append(bar *Bar) {
+	frame.Dates = append(frame.Dates, bar.Date)
+	frame.Times = append(frame.Times, bar.Time)
+	for c := ColOpen; int(c) < len(columnNames); c++ {
+		if col := frame.Column(c); col != nil && *col != nil {
+			*col = append(*col, bar.Value(c))
+		}
 	}
-	defer f.Close()
-	scanner := bufio.NewScanner(f)
-	frame := Frame{}
-	if head {
-		if scanner.Scan() != true {
-			return nil
+}
+
+// Bar 返回第i行, frame没有的列为0
+func (frame *Frame) Bar(i int) Bar {
+	bar := Bar{Date: frame.Dates[i]}
+	if i < len(frame.Times) {
+		bar.Time = frame.Times[i]
+	}
+	for c := ColOpen; int(c) < len(columnNames); c++ {
+		if col := frame.Column(c); col != nil && *col != nil {
+			*bar.field(c) = (*col)[i]
 		}
 	}
-	for scanner.Scan() {
-		record := strings.Fields(scanner.Text())
-		frame.Dates = append(frame.Dates, record[0])
-		var vs [6]float64
-		for i := range record[1:] {
-			vs[i], _ = strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+	return bar
+}
+
+// ReadCSV 读取csv文件。head为true时按第一行表头识别各列,
+// 否则按数据目录的布局(SchemaStockData)读取。
+// 出错时打印错误并返回nil, 无法解析的行被跳过; 需要区分错误类型时请使用Load。
+func ReadCSV(fname string, head bool) *Frame {
+	return readLenient(fname, &Options{Format: FormatCSV, Header: head})
+}
+
+// ReadTable 读取以空白分隔的表格文件。head为true时按表头识别各列,
+// 表头无法识别或没有表头时按SchemaTable读取。
+func ReadTable(fname string, head bool) *Frame {
+	return readLenient(fname, &Options{Format: FormatTable, Header: head})
+}
+
+func readLenient(fname string, opts *Options) *Frame {
+	opts.Lenient = true
+	opts.Warn = func(e *ParseError) { fmt.Println(e) }
+	frame, err := Load(context.Background(), fname, opts)
+	if err != nil {
+		if !errors.Is(err, ErrNoData) {
+			fmt.Println(err)
 		}
-		frame.Opens = append(frame.Opens, vs[0])
-		frame.Highs = append(frame.Highs, vs[1])
-		frame.Closes = append(frame.Closes, vs[2])
-		frame.Lows = append(frame.Lows, vs[3])
-		frame.Volumns = append(frame.Volumns, vs[4])
-		frame.Power = append(frame.Power, vs[5])
+		return nil
 	}
-	return &frame
+	return frame
 }
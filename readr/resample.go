@@ -0,0 +1,118 @@
+package readr
+
+import (
+	"fmt"
+	"stockstat/calendar"
+	"time"
+)
+
+// Resample 把日K线按周期p(周、月、季、年)合并, 返回新的Frame, frame不变。
+// 按日历划分周期, 节假日所在的周仍是一周; cal为nil时使用calendar.Default()。
+// 合并方式见merge。
+func (frame *Frame) Resample(p calendar.Period, cal *calendar.Calendar) (*Frame, error) {
+	if cal == nil {
+		cal = calendar.Default()
+	}
+	times, err := frame.ascendingTimes()
+	if err != nil {
+		return nil, err
+	}
+	return frame.merge(cal.Group(times, p)), nil
+}
+
+// ResampleDays 从第一行起把每n个交易日合并为一根K线, 合并方式同Resample。
+// 按交易日而不是行数计数, 停牌的日子也占位。
+func (frame *Frame) ResampleDays(n int, cal *calendar.Calendar) (*Frame, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("readr: resample by %d days", n)
+	}
+	if cal == nil {
+		cal = calendar.Default()
+	}
+	times, err := frame.ascendingTimes()
+	if err != nil {
+		return nil, err
+	}
+	return frame.merge(cal.GroupDays(times, n)), nil
+}
+
+// prevClose 由第i行的涨跌额或涨跌幅推算前一天的收盘, 两列都没有时ok为false
+func (frame *Frame) prevClose(i int) (prev float64, ok bool) {
+	switch {
+	case frame.Changes != nil:
+		return frame.Closes[i] - frame.Changes[i], true
+	case frame.PctChanges != nil && frame.PctChanges[i] != -100:
+		return frame.Closes[i] / (1 + frame.PctChanges[i]/100), true
+	}
+	return 0, false
+}
+
+// ascendingTimes 返回各行的日期, 日期不是升序时返回错误
+func (frame *Frame) ascendingTimes() ([]time.Time, error) {
+	times := make([]time.Time, frame.Len())
+	for i := range times {
+		times[i] = frame.time(i)
+		if i > 0 && times[i].Before(times[i-1]) {
+			return nil, fmt.Errorf("readr: %s: dates not in ascending order", frame.Dates[i])
+		}
+	}
+	return times, nil
+}
+
+// merge 把每组的各行合并为一根K线: 开盘为第一行的开盘, 最高、最低为组内的最高、最低,
+// 收盘为最后一行的收盘, 成交量、成交额、换手率为组内之和, 权值为最后一行的权值,
+// 日期为最后一行的日期。涨跌额、涨跌幅相对于组前一天的收盘重新计算; 均线各列去掉。
+//
+// 不复权的数据在组内有除权时, 各行价格先按权值换算到最后一行的价格再合并,
+// 使合并后的K线与其权值一致, 之后仍可以用Adjust复权。
+func (frame *Frame) merge(groups []calendar.Group) *Frame {
+	var cols []Column
+	for c := ColOpen; c < ColMA5; c++ {
+		if frame.Has(c) {
+			cols = append(cols, c)
+		}
+	}
+	res := NewFrame(cols, len(groups))
+	res.Adjustment, res.adjBase = frame.Adjustment, frame.adjBase
+
+	rescale := frame.Adjustment == AdjustNone && frame.Has(ColPower)
+	for _, g := range groups {
+		last := frame.Bar(g.Hi - 1)
+		// scale 把第i行的价格换算到last的价格
+		scale := func(i int) float64 {
+			if rescale && last.Power != 0 && frame.Power[i] != 0 {
+				return frame.Power[i] / last.Power
+			}
+			return 1
+		}
+
+		bar := last
+		bar.Open = frame.Opens[g.Lo] * scale(g.Lo)
+		bar.Volumn, bar.Amount, bar.Turnover = 0, 0, 0
+		for i := g.Lo; i < g.Hi; i++ {
+			f := scale(i)
+			if h := frame.Highs[i] * f; i == g.Lo || h > bar.High {
+				bar.High = h
+			}
+			if l := frame.Lows[i] * f; i == g.Lo || l < bar.Low {
+				bar.Low = l
+			}
+			bar.Volumn += frame.Volumns[i]
+			if frame.Amounts != nil {
+				bar.Amount += frame.Amounts[i]
+			}
+			if frame.Turnovers != nil {
+				bar.Turnover += frame.Turnovers[i]
+			}
+		}
+		if prev, ok := frame.prevClose(g.Lo); ok {
+			prev *= scale(g.Lo)
+			bar.Change, bar.PctChange = bar.Close-prev, 0
+			if prev != 0 {
+				bar.PctChange = bar.Change / prev * 100
+			}
+		}
+		res.Append(&bar)
+	}
+	return res
+}
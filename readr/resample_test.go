@@ -0,0 +1,151 @@
+package readr
+
+import (
+	"math"
+	"stockstat/calendar"
+	"testing"
+)
+
+// kline 是测试用的一行: 日期, 开盘, 最高, 收盘, 最低, 成交量, 权值
+type kline struct {
+	date                           string
+	open, high, close, low, volume float64
+	power                          float64
+}
+
+func klineFrame(rows []kline) *Frame {
+	frame := NewFrame([]Column{ColOpen, ColHigh, ColClose, ColLow, ColVolumn, ColPower}, len(rows))
+	for _, r := range rows {
+		t, err := ParseDate(r.date)
+		if err != nil {
+			panic(err)
+		}
+		frame.Append(&Bar{Date: r.date, Time: t, Open: r.open, High: r.high, Close: r.close, Low: r.low, Volumn: r.volume, Power: r.power})
+	}
+	return frame
+}
+
+func checkKlines(t *testing.T, name string, got *Frame, want []kline) {
+	t.Helper()
+	if got.Len() != len(want) {
+		t.Fatalf("%s: %d rows, want %d", name, got.Len(), len(want))
+	}
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	for i, w := range want {
+		b := got.Bar(i)
+		if b.Date != w.date || !near(b.Open, w.open) || !near(b.High, w.high) || !near(b.Close, w.close) ||
+			!near(b.Low, w.low) || !near(b.Volumn, w.volume) || !near(b.Power, w.power) {
+			t.Errorf("%s: row %d = %s %v %v %v %v %v %v, want %v", name, i,
+				b.Date, b.Open, b.High, b.Close, b.Low, b.Volumn, b.Power, w)
+		}
+	}
+}
+
+func TestResample(t *testing.T) {
+	tests := []struct {
+		name   string
+		period calendar.Period
+		rows   []kline
+		want   []kline
+	}{
+		{
+			// 2014年国庆10月1日至7日休市, 前后两周各只有两三天
+			name:   "national day weeks",
+			period: calendar.Week,
+			rows: []kline{
+				{"2014-09-29", 10, 11, 10.5, 9.5, 100, 1},
+				{"2014-09-30", 10.5, 12, 11, 10, 200, 1},
+				{"2014-10-08", 11, 11.5, 11.2, 10.8, 300, 1},
+				{"2014-10-09", 11.2, 13, 12, 11, 400, 1},
+				{"2014-10-10", 12, 12.5, 11.8, 10.5, 500, 1},
+			},
+			want: []kline{
+				{"2014-09-30", 10, 12, 11, 9.5, 300, 1},
+				{"2014-10-10", 11, 13, 11.8, 10.5, 1200, 1},
+			},
+		},
+		{
+			// 春节跨月, 1月30日是1月的最后一个交易日
+			name:   "spring festival months",
+			period: calendar.Month,
+			rows: []kline{
+				{"2014-01-29", 10, 10.5, 10.2, 9.8, 100, 1},
+				{"2014-01-30", 10.2, 10.4, 10.3, 10.1, 100, 1},
+				{"2014-02-07", 10.3, 10.8, 10.6, 10.2, 100, 1},
+			},
+			want: []kline{
+				{"2014-01-30", 10, 10.5, 10.3, 9.8, 200, 1},
+				{"2014-02-07", 10.3, 10.8, 10.6, 10.2, 100, 1},
+			},
+		},
+		{
+			// 周三10转10, 之前的价格按权值换算到除权后再合并
+			name:   "ex-rights within week",
+			period: calendar.Week,
+			rows: []kline{
+				{"2014-03-03", 20, 22, 21, 19, 100, 1},
+				{"2014-03-04", 21, 24, 23, 20, 100, 1},
+				{"2014-03-05", 11, 11.8, 11, 10.5, 300, 2},
+			},
+			want: []kline{
+				{"2014-03-05", 10, 12, 11, 9.5, 500, 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		frame := klineFrame(tt.rows)
+		got, err := frame.Resample(tt.period, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		checkKlines(t, tt.name, got, tt.want)
+		checkKlines(t, tt.name+" source", frame, tt.rows)
+	}
+}
+
+func TestResampleChange(t *testing.T) {
+	frame := NewFrame([]Column{ColOpen, ColHigh, ColClose, ColLow, ColVolumn, ColChange, ColPctChange}, 2)
+	for _, s := range []struct {
+		date   string
+		close  float64
+		change float64
+	}{{"2014-03-03", 11, 1}, {"2014-03-04", 12.1, 1.1}} {
+		t, _ := ParseDate(s.date)
+		frame.Append(&Bar{Date: s.date, Time: t, Open: s.close, High: s.close, Close: s.close, Low: s.close,
+			Volumn: 1, Change: s.change, PctChange: s.change / (s.close - s.change) * 100})
+	}
+	got, err := frame.Resample(calendar.Week, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 相对于周前一天的收盘10
+	if b := got.Bar(0); math.Abs(b.Change-2.1) > 1e-9 || math.Abs(b.PctChange-21) > 1e-9 {
+		t.Errorf("change %v, pct %v, want 2.1, 21", b.Change, b.PctChange)
+	}
+}
+
+func TestResampleDays(t *testing.T) {
+	// 3月5日停牌, 仍占一个交易日, 之后的组不错位
+	frame := klineFrame([]kline{
+		{"2014-03-03", 10, 11, 10, 9, 100, 1},
+		{"2014-03-04", 10, 12, 11, 10, 100, 1},
+		{"2014-03-06", 11, 12, 12, 11, 100, 1},
+		{"2014-03-07", 12, 13, 13, 12, 100, 1},
+	})
+	got, err := frame.ResampleDays(2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKlines(t, "days", got, []kline{
+		{"2014-03-04", 10, 12, 11, 9, 200, 1},
+		{"2014-03-06", 11, 12, 12, 11, 100, 1},
+		{"2014-03-07", 12, 13, 13, 12, 100, 1},
+	})
+	if _, err := frame.ResampleDays(0, nil); err == nil {
+		t.Error("ResampleDays(0) succeeded")
+	}
+	frame.Times[1], frame.Times[2] = frame.Times[2], frame.Times[1]
+	if _, err := frame.Resample(calendar.Week, nil); err == nil {
+		t.Error("Resample of descending dates succeeded")
+	}
+}
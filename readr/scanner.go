@@ -0,0 +1,523 @@
+package readr
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bar 是一根K线, 即数据文件中的一行。文件中没有的列为0, 用Scanner.Has判断。
+type Bar struct {
+	Date                   string
+	Time                   time.Time
+	Open, High, Close, Low float64
+	Volumn, Amount         float64
+	Power                  float64
+	Change, PctChange      float64
+	Turnover               float64
+	MA5, MA10, MA20        float64
+	VMA5, VMA10, VMA20     float64
+}
+
+// field 返回列c在bar中对应的字段, ColDate及ColSkip返回nil
+func (bar *Bar) field(c Column) *float64 {
+	switch c {
+	case ColOpen:
+		return &bar.Open
+	case ColHigh:
+		return &bar.High
+	case ColClose:
+		return &bar.Close
+	case ColLow:
+		return &bar.Low
+	case ColVolumn:
+		return &bar.Volumn
+	case ColAmount:
+		return &bar.Amount
+	case ColPower:
+		return &bar.Power
+	case ColChange:
+		return &bar.Change
+	case ColPctChange:
+		return &bar.PctChange
+	case ColTurnover:
+		return &bar.Turnover
+	case ColMA5:
+		return &bar.MA5
+	case ColMA10:
+		return &bar.MA10
+	case ColMA20:
+		return &bar.MA20
+	case ColVMA5:
+		return &bar.VMA5
+	case ColVMA10:
+		return &bar.VMA10
+	case ColVMA20:
+		return &bar.VMA20
+	}
+	return nil
+}
+
+// Value 返回列c的值
+func (bar *Bar) Value(c Column) float64 {
+	if p := bar.field(c); p != nil {
+		return *p
+	}
+	return 0
+}
+
+// setRecord 按布局schema解析一行记录, 出错时返回出错的列号(从0开始)
+func (bar *Bar) setRecord(schema *Schema, record []string) (int, error) {
+	*bar = Bar{}
+	if len(record) < len(schema.Columns) {
+		return len(record), fmt.Errorf("want %d fields, got %d", len(schema.Columns), len(record))
+	}
+	for j, c := range schema.Columns {
+		if c == ColDate {
+			t, err := ParseDate(strings.TrimSpace(record[j]))
+			if err != nil {
+				return j, err
+			}
+			bar.Time = t
+			bar.Date = t.Format(DateLayout)
+			continue
+		}
+		p := bar.field(c)
+		if p == nil {
+			continue
+		}
+		x, err := ParseNumber(record[j])
+		if err != nil {
+			return j, err
+		}
+		*p = x
+	}
+	return 0, nil
+}
+
+// rowSource 是某种格式数据文件的逐行读取器
+type rowSource interface {
+	// read 把下一行读入bar并返回其行号。没有更多数据时返回io.EOF;
+	// 可以跳过的坏行返回*ParseError, 其它错误无法继续读取。
+	read(bar *Bar) (int, error)
+	// columns 返回文件中有的列
+	columns() []Column
+}
+
+// Scanner 逐行读取数据文件, 内存占用与文件长度无关。用法:
+//
+//	s, err := readr.OpenScanner(ctx, path, &readr.Options{Header: true, From: start})
+//	if err != nil { ... }
+//	defer s.Close()
+//	for s.Scan() {
+//		bar := s.Bar()
+//		...
+//	}
+//	if err := s.Err(); err != nil { ... }
+//
+// Options.From/To限定日期范围, 数据须按日期升序排列。
+type Scanner struct {
+	ctx    context.Context
+	opts   *Options
+	src    rowSource
+	format Format
+	name   string
+	closer io.Closer
+	bar    Bar
+	line   int
+	rows   int // 已读行数, 用于定期检查ctx
+	err    error
+	done   bool
+
+	adjBase float64 // 前复权的基准权值
+}
+
+// OpenScanner 打开数据文件path。文件不存在时返回的错误满足errors.Is(err, ErrNotFound)。
+// 若有不比path旧且读取选项相同的二进制缓存(见CachePath), 则改为读取缓存, 此时整个缓存读入内存;
+// 缓存损坏时返回ErrBadBinary, 可用UpdateCache重新生成。
+// 前复权时先读一遍文件以取得最后的权值。
+func OpenScanner(ctx context.Context, path string, opts *Options) (*Scanner, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	s, err := openScanner(ctx, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAdjust(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	if opts.Adjust == AdjustForward {
+		if s.adjBase, err = lastPower(ctx, path, opts); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func openScanner(ctx context.Context, path string, opts *Options) (*Scanner, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !opts.NoCache && cacheFresh(path) {
+		frame, err := loadCache(path, opts)
+		switch {
+		case err == nil:
+			src := newFrameSource(frame)
+			if !opts.From.IsZero() { // Times是升序的
+				src.i = sort.Search(frame.Len(), func(i int) bool { return !frame.Times[i].Before(opts.From) })
+			}
+			return &Scanner{ctx: ctx, opts: opts, name: path, src: src}, nil
+		case !errors.Is(err, errStaleCache) && !os.IsNotExist(err):
+			// 损坏的缓存不应被默默忽略, 删除它或用bincache重新生成
+			return nil, err
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+		}
+		return nil, err
+	}
+	s, err := newScanner(ctx, f, path, opts)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.closer = f
+	return s, nil
+}
+
+// lastPower 返回文件path最后一行的权值
+func lastPower(ctx context.Context, path string, opts *Options) (float64, error) {
+	o := *opts
+	o.Adjust, o.From, o.To, o.Warn = AdjustNone, time.Time{}, time.Time{}, nil
+	s, err := openScanner(ctx, path, &o)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+	last := 0.0
+	for s.Scan() {
+		last = s.Bar().Power
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+	if last <= 0 {
+		return 0, fmt.Errorf("%s: last pow is %v, can't adjust forward", path, last)
+	}
+	return last, nil
+}
+
+// checkAdjust 检查复权所需的权值列
+func (s *Scanner) checkAdjust() error {
+	if s.opts.Adjust != AdjustNone && !s.Has(ColPower) {
+		return fmt.Errorf("%s: %w", s.name, ErrNoPower)
+	}
+	return nil
+}
+
+// NewScanner 从r读取数据, name用于错误信息及按扩展名判断格式。
+// r只能读一遍, 因此不支持前复权, 请改用OpenScanner或Frame.Adjust。
+func NewScanner(ctx context.Context, r io.Reader, name string, opts *Options) (*Scanner, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.Adjust == AdjustForward {
+		return nil, fmt.Errorf("%s: forward adjustment needs OpenScanner", name)
+	}
+	s, err := newScanner(ctx, r, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAdjust(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newScanner(ctx context.Context, r io.Reader, name string, opts *Options) (*Scanner, error) {
+	rd := bufio.NewReader(r)
+	format := opts.Format
+	if format == FormatAuto {
+		format = detectFormat(name, rd)
+	}
+	s := &Scanner{ctx: ctx, opts: opts, name: name, format: format}
+	var err error
+	switch format {
+	case FormatIfeng:
+		src := newIfengSource(rd, name)
+		src.from = fromPrefix(opts)
+		if err = src.seek(); err == io.EOF {
+			err = nil
+		}
+		s.src = src
+	case FormatTable:
+		s.src, err = newTableSource(rd, name, opts)
+	default:
+		s.src, err = newCSVSource(rd, name, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Scan 读取下一行, 没有更多数据或出错时返回false
+func (s *Scanner) Scan() bool {
+	for s.err == nil && !s.done {
+		s.rows++
+		if s.rows%ctxCheckRows == 0 {
+			if s.err = s.ctx.Err(); s.err != nil {
+				break
+			}
+		}
+		line, err := s.src.read(&s.bar)
+		if err == nil && s.opts.Adjust != AdjustNone && s.bar.Power <= 0 {
+			err = &ParseError{File: s.name, Line: line, Field: ColPower.String(), Err: errBadPower}
+		}
+		if err == io.EOF {
+			s.done = true
+			break
+		}
+		if err != nil {
+			if pe, ok := err.(*ParseError); ok && s.opts.Lenient {
+				if s.opts.Warn != nil {
+					s.opts.Warn(pe)
+				}
+				continue
+			}
+			s.err = err
+			break
+		}
+		if !s.opts.From.IsZero() && s.bar.Time.Before(s.opts.From) {
+			continue
+		}
+		if !s.opts.To.IsZero() && s.bar.Time.After(s.opts.To) {
+			s.done = true
+			break
+		}
+		s.line = line
+		s.bar.adjust(s.opts.Adjust, s.adjBase)
+		return true
+	}
+	return false
+}
+
+// Bar 返回当前行。返回的Bar在下次调用Scan时被覆盖, 需要保留时应复制。
+func (s *Scanner) Bar() *Bar {
+	return &s.bar
+}
+
+// Line 返回当前行在文件中的行号
+func (s *Scanner) Line() int {
+	return s.line
+}
+
+// Err 返回读取过程中遇到的第一个错误, 正常结束时为nil
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Format 返回文件的格式, 读取二进制缓存时为FormatAuto
+func (s *Scanner) Format() Format {
+	return s.format
+}
+
+// Columns 返回文件中有的列
+func (s *Scanner) Columns() []Column {
+	return s.src.columns()
+}
+
+// Has 报告文件中是否有列c
+func (s *Scanner) Has(c Column) bool {
+	for _, col := range s.src.columns() {
+		if col == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Close 关闭由OpenScanner打开的文件
+func (s *Scanner) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	err := s.closer.Close()
+	s.closer = nil
+	return err
+}
+
+// csvSource 读取逗号分隔的文件
+type csvSource struct {
+	reader *csv.Reader
+	schema *Schema
+	file   string
+	from   string // 早于此日期的行不解析, 见before
+}
+
+func newCSVSource(r io.Reader, file string, opts *Options) (*csvSource, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	schema, err := optsSchema(file, opts, SchemaStockData)
+	if err != nil {
+		return nil, err
+	}
+	src := &csvSource{reader: reader, schema: schema, file: file, from: fromPrefix(opts)}
+	if opts.Header {
+		header, err := reader.Read()
+		for err == nil && isMarker(header[0]) {
+			header, err = reader.Read()
+		}
+		if err == io.EOF {
+			return src, nil
+		}
+		if err != nil {
+			return nil, &SchemaError{file, err}
+		}
+		src.schema, err = DetectSchema(header)
+		if err != nil {
+			return nil, &SchemaError{file, err}
+		}
+	}
+	return src, nil
+}
+
+func (src *csvSource) columns() []Column {
+	return src.schema.Columns
+}
+
+func (src *csvSource) read(bar *Bar) (int, error) {
+	for {
+		record, err := src.reader.Read()
+		if err != nil {
+			if pe, ok := err.(*csv.ParseError); ok {
+				return pe.Line, &ParseError{File: src.file, Line: pe.Line, Column: pe.Column, Err: pe.Err}
+			}
+			return 0, err
+		}
+		line, _ := src.reader.FieldPos(0)
+		if strings.HasPrefix(record[0], "#") || before(record[0], src.from) { // 注释, 如sina2ifeng写入的"#date,..."表头
+			continue
+		}
+		return line, parseErr(src.file, line, src.schema, record, bar)
+	}
+}
+
+// tableSource 读取以空白分隔的文件
+type tableSource struct {
+	scanner *bufio.Scanner
+	schema  *Schema
+	file    string
+	line    int
+	from    string // 早于此日期的行不解析, 见before
+}
+
+func newTableSource(r io.Reader, file string, opts *Options) (*tableSource, error) {
+	schema, err := optsSchema(file, opts, SchemaTable)
+	if err != nil {
+		return nil, err
+	}
+	src := &tableSource{scanner: bufio.NewScanner(r), schema: schema, file: file, from: fromPrefix(opts)}
+	for opts.Header && src.scanner.Scan() {
+		src.line++
+		if isMarker(src.scanner.Text()) {
+			continue
+		}
+		// "deal money"含有空格, 先合并再按空白切分
+		header := strings.Replace(src.scanner.Text(), "deal money", "dealmoney", -1)
+		if src.schema, err = DetectSchema(strings.Fields(header)); err != nil {
+			return nil, &SchemaError{file, err}
+		}
+		break
+	}
+	return src, nil
+}
+
+func (src *tableSource) columns() []Column {
+	return src.schema.Columns
+}
+
+func (src *tableSource) read(bar *Bar) (int, error) {
+	for src.scanner.Scan() {
+		src.line++
+		if before(strings.TrimLeft(src.scanner.Text(), " \t"), src.from) {
+			continue
+		}
+		record := strings.Fields(src.scanner.Text())
+		if len(record) == 0 || isMarker(record[0]) {
+			continue
+		}
+		return src.line, parseErr(src.file, src.line, src.schema, record, bar)
+	}
+	if err := src.scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, io.EOF
+}
+
+// fromPrefix 返回不早于opts.From的第一天的DateLayout格式, 不限起始日期时返回空串
+func fromPrefix(opts *Options) string {
+	if opts.From.IsZero() {
+		return ""
+	}
+	t := opts.From.UTC()
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if d.Before(t) { // 数据中的日期是当天零点
+		d = d.AddDate(0, 0, 1)
+	}
+	return d.Format(DateLayout)
+}
+
+// before 报告以DateLayout格式日期开头的原始数据s是否早于from, 用于在解析前跳过From之前的行。
+// 其他格式的日期返回false, 解析后再由Scanner比较。
+func before(s, from string) bool {
+	n := len(DateLayout)
+	if from == "" || len(s) < n || s[4] != '-' || s[7] != '-' {
+		return false
+	}
+	if len(s) > n && s[n] != ',' && s[n] != ' ' && s[n] != '\t' {
+		return false
+	}
+	return s[:n] < from
+}
+
+// parseErr 解析一行记录到bar, 出错时返回带位置的*ParseError
+func parseErr(file string, line int, schema *Schema, record []string, bar *Bar) error {
+	col, err := bar.setRecord(schema, record)
+	if err == nil {
+		return nil
+	}
+	e := &ParseError{File: file, Line: line, Column: col + 1, Err: err}
+	if col < len(schema.Columns) {
+		e.Field = schema.Columns[col].String()
+	}
+	return e
+}
+
+// optsSchema 返回opts指定的布局, 未指定时返回名为def的布局
+func optsSchema(file string, opts *Options, def string) (*Schema, error) {
+	name := opts.Schema
+	if name == "" {
+		name = def
+	}
+	s := LookupSchema(name)
+	if s == nil {
+		return nil, &SchemaError{file, fmt.Errorf("unknown schema %q", name)}
+	}
+	return s, nil
+}
@@ -0,0 +1,153 @@
+package readr
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// rangeData 的第二行无法解析, 读取2024-01-03及以后时不应报告
+const rangeData = "date,open,high,close,low,volume,pow\n" +
+	"2024-01-02,10,10.5,10.2,9.8,1000,1\n" +
+	"2024-01-03,bad,10.5,10.2,9.8,1000,1\n" +
+	"2024-01-04,10.2,10.6,10.4,10.1,1200,1\n" +
+	"2024-01-05,10.4,10.9,10.8,10.3,1500,1\n" +
+	"2024-01-08,10.8,11.0,10.9,10.6,1800,1\n"
+
+const rangeTable = "2024-01-02\t10\t10.5\t10.2\t9.8\t1000\t10000\t1\n" +
+	"2024-01-03\tbad\t10.5\t10.2\t9.8\t1000\t10000\t1\n" +
+	"  2024-01-04\t10.2\t10.6\t10.4\t10.1\t1200\t12000\t1\n" +
+	"2024-01-05\t10.4\t10.9\t10.8\t10.3\t1500\t15000\t1\n" +
+	"2024-01-08\t10.8\t11.0\t10.9\t10.6\t1800\t18000\t1\n"
+
+const rangeIfeng = `{"record":[` +
+	`["2024-01-02","10","10.5","10.2","9.8","1000","0","0","0","0","0","0","0","0","0"],` +
+	`["2024-01-03","bad","10.5","10.2","9.8","1000","0","0","0","0","0","0","0","0","0"],` +
+	`["2024-01-04","10.2","10.6","10.4","10.1","1200","0","0","0","0","0","0","0","0","0"],` +
+	`["2024-01-05","10.4","10.9","10.8","10.3","1500","0","0","0","0","0","0","0","0","0"],` +
+	`["2024-01-08","10.8","11.0","10.9","10.6","1800","0","0","0","0","0","0","0","0","0"]]}`
+
+func TestScannerRange(t *testing.T) {
+	ctx := context.Background()
+	files := []struct {
+		name string
+		path string
+		opts Options
+	}{
+		{"csv", writeFile(t, "600000.csv", rangeData), Options{Header: true, NoCache: true}},
+		{"table", writeFile(t, "600000", rangeTable), Options{Format: FormatTable}},
+		{"ifeng", writeFile(t, "600000.json", rangeIfeng), Options{Format: FormatIfeng}},
+	}
+	tests := []struct {
+		from, to string
+		want     []string
+	}{
+		{"2024-01-04", "", []string{"2024-01-04", "2024-01-05", "2024-01-08"}},
+		{"2024-01-03 12:00", "2024-01-05", []string{"2024-01-04", "2024-01-05"}},
+		{"2024-01-04", "2024-01-04", []string{"2024-01-04"}},
+		{"2024-01-06", "2024-01-07", nil}, // 范围内没有数据
+		{"2024-01-09", "", nil},           // From晚于所有数据
+		{"2024-01-08", "2024-01-06", nil}, // To早于From
+	}
+	for _, f := range files {
+		for _, tt := range tests {
+			opts := f.opts
+			opts.From = day(tt.from[:10])
+			if len(tt.from) > 10 {
+				opts.From = opts.From.Add(12 * time.Hour)
+			}
+			if tt.to != "" {
+				opts.To = day(tt.to)
+			}
+			s, err := OpenScanner(ctx, f.path, &opts)
+			if err != nil {
+				t.Fatalf("%s: %v", f.name, err)
+			}
+			var got []string
+			for s.Scan() {
+				got = append(got, s.Bar().Date)
+			}
+			s.Close()
+			if err := s.Err(); err != nil {
+				t.Errorf("%s %s..%s: %v (rows before From must not be parsed)", f.name, tt.from, tt.to, err)
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && (got[0] != tt.want[0] || got[len(got)-1] != tt.want[len(tt.want)-1])) {
+				t.Errorf("%s %s..%s: dates %v, want %v", f.name, tt.from, tt.to, got, tt.want)
+			}
+			if _, err := Load(ctx, f.path, &opts); tt.want == nil && !errors.Is(err, ErrNoData) {
+				t.Errorf("%s %s..%s: Load err = %v, want ErrNoData", f.name, tt.from, tt.to, err)
+			}
+		}
+	}
+}
+
+func TestScannerRangeCache(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "600000.csv")
+	writeCSVFile(t, path, testFrame(10)) // 2024-01-02起的10天
+	opts := &Options{Header: true}
+	if _, err := UpdateCache(ctx, path, opts, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		from, to    string
+		first, last string
+		n           int
+	}{
+		{"2024-01-05", "2024-01-07", "2024-01-05", "2024-01-07", 3},
+		{"2024-01-01", "", "2024-01-02", "2024-01-11", 10},
+		{"2024-01-11", "", "2024-01-11", "2024-01-11", 1},
+		{"2024-01-12", "", "", "", 0},
+	} {
+		o := *opts
+		o.From = day(tt.from)
+		if tt.to != "" {
+			o.To = day(tt.to)
+		}
+		frm, err := Load(ctx, path, &o)
+		if tt.n == 0 {
+			if !errors.Is(err, ErrNoData) {
+				t.Errorf("%s..%s: err = %v, want ErrNoData", tt.from, tt.to, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frm.Len() != tt.n || frm.Dates[0] != tt.first || frm.Dates[frm.Len()-1] != tt.last {
+			t.Errorf("%s..%s: dates %v, want %s..%s", tt.from, tt.to, frm.Dates, tt.first, tt.last)
+		}
+	}
+}
+
+func TestBefore(t *testing.T) {
+	tests := []struct {
+		s, from string
+		want    bool
+	}{
+		{"2024-01-02", "2024-01-03", true},
+		{"2024-01-03", "2024-01-03", false},
+		{"2024-01-02,10", "2024-01-03", true},
+		{"2024-01-02\t10", "2024-01-03", true},
+		{"2024-01-02", "", false},
+		{"20240102", "2024-01-03", false}, // 其他格式解析后再比较
+		{"2024/01/02", "2024-01-03", false},
+		{"2024-01-0212", "2024-01-03", false},
+		{"#date", "2024-01-03", false},
+	}
+	for _, tt := range tests {
+		if got := before(tt.s, tt.from); got != tt.want {
+			t.Errorf("before(%q, %q) = %v, want %v", tt.s, tt.from, got, tt.want)
+		}
+	}
+}
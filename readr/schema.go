@@ -0,0 +1,240 @@
+package readr
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Column 标识数据文件中的一列对应Frame的哪个字段
+type Column int
+
+const (
+	ColSkip Column = iota // 不认识或不需要的列
+	ColDate
+	ColOpen
+	ColHigh
+	ColClose
+	ColLow
+	ColVolumn
+	ColAmount // 成交额
+	ColPower
+	ColChange
+	ColPctChange
+	ColTurnover
+	ColMA5
+	ColMA10
+	ColMA20
+	ColVMA5
+	ColVMA10
+	ColVMA20
+)
+
+var columnNames = [...]string{
+	ColSkip:      "-",
+	ColDate:      "date",
+	ColOpen:      "open",
+	ColHigh:      "high",
+	ColClose:     "close",
+	ColLow:       "low",
+	ColVolumn:    "volume",
+	ColAmount:    "amount",
+	ColPower:     "pow",
+	ColChange:    "change",
+	ColPctChange: "pct",
+	ColTurnover:  "turnover",
+	ColMA5:       "ma5",
+	ColMA10:      "ma10",
+	ColMA20:      "ma20",
+	ColVMA5:      "vma5",
+	ColVMA10:     "vma10",
+	ColVMA20:     "vma20",
+}
+
+func (c Column) String() string {
+	if c < 0 || int(c) >= len(columnNames) {
+		return fmt.Sprintf("Column(%d)", int(c))
+	}
+	return columnNames[c]
+}
+
+// columnAliases 把表头中出现过的各种写法(含中文及拼写错误)映射到Column
+var columnAliases = map[string]Column{
+	"date": ColDate, "day": ColDate, "日期": ColDate,
+	"open": ColOpen, "开盘": ColOpen, "开盘价": ColOpen,
+	"high": ColHigh, "最高": ColHigh, "最高价": ColHigh,
+	"close": ColClose, "cloe": ColClose, "收盘": ColClose, "收盘价": ColClose,
+	"low": ColLow, "最低": ColLow, "最低价": ColLow,
+	"volume": ColVolumn, "volumn": ColVolumn, "vol": ColVolumn, "成交量": ColVolumn,
+	"amount": ColAmount, "transaction": ColAmount, "deal money": ColAmount,
+	"dealmoney": ColAmount, "money": ColAmount, "成交额": ColAmount, "成交金额": ColAmount,
+	"pow": ColPower, "power": ColPower, "权": ColPower, "权值": ColPower, "复权因子": ColPower,
+	"change": ColChange, "涨跌额": ColChange,
+	"pct": ColPctChange, "pctchange": ColPctChange, "p_change": ColPctChange, "涨跌幅": ColPctChange,
+	"turnover": ColTurnover, "换手率": ColTurnover,
+	"ma5": ColMA5, "ma10": ColMA10, "ma20": ColMA20,
+	"vma5": ColVMA5, "vma10": ColVMA10, "vma20": ColVMA20,
+}
+
+// requiredColumns 是任何数据文件都必须包含的列
+var requiredColumns = []Column{ColDate, ColOpen, ColHigh, ColClose, ColLow, ColVolumn}
+
+// LookupColumn 返回表头名name对应的Column, 不认识的名字返回ColSkip
+func LookupColumn(name string) Column {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimLeft(name, "#\ufeff")
+	return columnAliases[strings.TrimSpace(name)]
+}
+
+// Schema 描述一种数据文件的列布局, Columns[i]是文件第i列对应的Frame字段
+type Schema struct {
+	Name    string
+	Columns []Column
+}
+
+// Index 返回列c在文件中的位置, 不存在时返回-1
+func (s *Schema) Index(c Column) int {
+	for i, col := range s.Columns {
+		if col == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// Has 报告文件中是否有列c
+func (s *Schema) Has(c Column) bool {
+	return s.Index(c) >= 0
+}
+
+// Header 返回该布局的规范表头
+func (s *Schema) Header() []string {
+	names := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		names[i] = c.String()
+	}
+	return names
+}
+
+// Validate 检查是否缺少必需的列, 以及是否有重复的列
+func (s *Schema) Validate() error {
+	seen := make(map[Column]bool)
+	for _, c := range s.Columns {
+		if c == ColSkip {
+			continue
+		}
+		if seen[c] {
+			return fmt.Errorf("schema %s: duplicated column %s", s.Name, c)
+		}
+		seen[c] = true
+	}
+	var missing []string
+	for _, c := range requiredColumns {
+		if !seen[c] {
+			missing = append(missing, c.String())
+		}
+	}
+	if missing != nil {
+		return fmt.Errorf("schema %s: missing column %s", s.Name, strings.Join(missing, ","))
+	}
+	return nil
+}
+
+// 已知的数据文件布局
+const (
+	// SchemaStockData 是数据目录中<code>.csv的布局(sina2ifeng的输出), 表头以"#date"开头
+	SchemaStockData = "stockdata"
+	// SchemaDownload 是下载得到的原始数据布局, 含成交额
+	SchemaDownload = "download"
+	// SchemaTable 是以空白分隔的表格文件布局(见stat/doc.md), 与SchemaDownload相同, 是其别名
+	SchemaTable = "table"
+	// SchemaIfeng 是凤凰网日K线JSON的字段顺序
+	SchemaIfeng = "ifeng"
+)
+
+// ifengColumns 是凤凰网日K线JSON中各字段的顺序
+var ifengColumns = []Column{
+	ColDate, ColOpen, ColHigh, ColClose, ColLow, ColVolumn, ColChange, ColPctChange,
+	ColMA5, ColMA10, ColMA20, ColVMA5, ColVMA10, ColVMA20, ColTurnover,
+}
+
+var (
+	schemaMu sync.RWMutex
+	schemas  []*Schema // 按登记顺序保存, DetectSchema优先匹配先登记的布局
+)
+
+// schemaAliases 是布局的别名, 别名不单独登记, 以免DetectSchema在列相同的布局间任意选择
+var schemaAliases = map[string]string{
+	SchemaTable: SchemaDownload,
+}
+
+func init() {
+	RegisterSchema(&Schema{SchemaStockData, []Column{
+		ColDate, ColOpen, ColHigh, ColClose, ColLow, ColVolumn, ColPower}})
+	RegisterSchema(&Schema{SchemaDownload, []Column{
+		ColDate, ColOpen, ColHigh, ColClose, ColLow, ColVolumn, ColAmount, ColPower}})
+	RegisterSchema(&Schema{SchemaIfeng, ifengColumns})
+}
+
+// RegisterSchema 登记一种数据文件布局, 同名的布局会被替换
+func RegisterSchema(s *Schema) {
+	if err := s.Validate(); err != nil {
+		panic(err)
+	}
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	for i, old := range schemas {
+		if old.Name == s.Name {
+			schemas[i] = s
+			return
+		}
+	}
+	schemas = append(schemas, s)
+}
+
+// LookupSchema 按名字或别名查找已登记的布局, 找不到时返回nil
+func LookupSchema(name string) *Schema {
+	if n, ok := schemaAliases[name]; ok {
+		name = n
+	}
+	schemaMu.RLock()
+	defer schemaMu.RUnlock()
+	for _, s := range schemas {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// DetectSchema 根据表头建立布局。若与某个已登记的布局相同则返回该布局,
+// 否则返回一个名为"header"的新布局。缺少必需列时返回错误。
+func DetectSchema(header []string) (*Schema, error) {
+	s := &Schema{Name: "header", Columns: make([]Column, len(header))}
+	for i, name := range header {
+		s.Columns[i] = LookupColumn(name)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("header %q: %v", strings.Join(header, ","), err)
+	}
+	schemaMu.RLock()
+	defer schemaMu.RUnlock()
+	for _, known := range schemas {
+		if sameColumns(known.Columns, s.Columns) {
+			return known, nil
+		}
+	}
+	return s, nil
+}
+
+func sameColumns(a, b []Column) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package readr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchemaTableAlias(t *testing.T) {
+	table, download := LookupSchema(SchemaTable), LookupSchema(SchemaDownload)
+	if table == nil || table != download {
+		t.Fatalf("LookupSchema(%q) = %v, want the %q schema", SchemaTable, table, SchemaDownload)
+	}
+	s, err := DetectSchema([]string{"date", "open", "high", "close", "low", "volume", "amount", "pow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != SchemaDownload {
+		t.Errorf("DetectSchema = %q, want %q", s.Name, SchemaDownload)
+	}
+	for _, known := range schemas {
+		if known.Name != s.Name && sameColumns(known.Columns, s.Columns) {
+			t.Errorf("schemas %q and %q have the same columns", known.Name, s.Name)
+		}
+	}
+}
+
+func TestIsLegacy(t *testing.T) {
+	tests := []struct {
+		name, data string
+		want       bool
+	}{
+		{"legacy", "#date,open,high,cloe,low,volumn,pow\r\n2014-01-02,10,11,10.5,9.5,1000,2\r\n", true},
+		{"bom", "\ufeff#date,open,high,close,low,volume,pow\n", true},
+		{"marked", "#stockstat v1 adjust=none\r\n#date,open,high,cloe,low,volumn,pow\r\n", false},
+		{"download", "date,open,high,close,low,volume,amount,pow\n", false},
+		{"download comment", "#date,open,high,close,low,volume,amount,pow\n", false},
+		{"no comment", "date,open,high,close,low,volume,pow\n", false},
+		{"empty", "", false},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, "600000.csv")
+		if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := IsLegacy(path); err != nil || got != tt.want {
+			t.Errorf("%s: IsLegacy = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestTableHeader(t *testing.T) {
+	ctx := context.Background()
+	opts := &Options{Format: FormatTable, Header: true}
+	missing := "date open high low volume amount pow\n2004-01-02 16.006 16.406 15.883 11565225 121756888 1.539\n"
+	_, err := NewScanner(ctx, strings.NewReader(missing), "600000", opts)
+	var serr *SchemaError
+	if !errors.As(err, &serr) || serr.File != "600000" {
+		t.Fatalf("header without close: err = %v, want *SchemaError", err)
+	}
+	if !strings.Contains(err.Error(), "close") {
+		t.Errorf("error %q does not name the missing column", err)
+	}
+
+	good := "date open high close low volume deal money pow\n2004-01-02 16.006 16.406 16.160 15.883 11565225 121756888 1.539\n"
+	s, err := NewScanner(ctx, strings.NewReader(good), "600000", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Scan() || s.Bar().Close != 16.16 || s.Bar().Amount != 121756888 {
+		t.Errorf("Bar = %+v, %v", s.Bar(), s.Err())
+	}
+}
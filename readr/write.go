@@ -0,0 +1,197 @@
+package readr
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteOptions 控制Frame的写出格式。零值写出frame中有的全部列,
+// 使用规范列名及最短的无损数字表示, 行尾为"\n"。
+// 按零值写出的文件由Load(Header: true)读回, 得到的Frame与原来完全相同。
+type WriteOptions struct {
+	// Columns 指定写出的列及其顺序, 为nil时按Column顺序写出frame中有的全部列。
+	// ColDate总是写在第一列。
+	Columns []Column
+	// Precision 是小数位数, 0或负数表示最短的无损表示
+	Precision int
+	// Digits 为个别列指定小数位数, 优先于Precision
+	Digits map[Column]int
+	// Names 替换个别列的表头名; 要能读回, 名字须能被LookupColumn识别
+	Names map[Column]string
+	// NoHeader 为true时不写表头
+	NoHeader bool
+	// Comment 为true时表头以'#'开头, 如数据目录中的"#date,open,..."
+	Comment bool
+	// CRLF 为true时行尾为"\r\n"
+	CRLF bool
+	// Marker 为true时先写出标记(见Marker), 表明文件已由stockstat转换过
+	Marker bool
+}
+
+// StockDataFormat 是数据目录中<code>.csv的写出格式(SchemaStockData), 带有标记,
+// 表明其中是不复权的价格
+var StockDataFormat = WriteOptions{
+	Columns: []Column{ColDate, ColOpen, ColHigh, ColClose, ColLow, ColVolumn, ColPower},
+	Digits: map[Column]int{
+		ColOpen: 3, ColHigh: 3, ColClose: 3, ColLow: 3,
+		ColVolumn: 0, ColPower: 3,
+	},
+	Comment: true,
+	CRLF:    true,
+	Marker:  true,
+}
+
+func (opts *WriteOptions) columns(frame *Frame) []Column {
+	cols := []Column{ColDate}
+	if opts.Columns == nil {
+		for c := ColOpen; int(c) < len(columnNames); c++ {
+			if frame.Has(c) {
+				cols = append(cols, c)
+			}
+		}
+		return cols
+	}
+	for _, c := range opts.Columns {
+		if c != ColDate && frame.Column(c) != nil {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+func (opts *WriteOptions) header(cols []Column) []string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.String()
+		if name, ok := opts.Names[c]; ok {
+			names[i] = name
+		}
+	}
+	if opts.Comment {
+		names[0] = "#" + names[0]
+	}
+	return names
+}
+
+func (opts *WriteOptions) format(c Column, x float64) string {
+	prec := opts.Precision
+	if d, ok := opts.Digits[c]; ok {
+		prec = d
+	} else if prec <= 0 {
+		prec = -1
+	}
+	return strconv.FormatFloat(x, 'f', prec, 64)
+}
+
+// record 返回第i行各列的文本, frame中没有的列写为0
+func (opts *WriteOptions) record(frame *Frame, cols []Column, i int, record []string) []string {
+	record = record[:0]
+	for _, c := range cols {
+		if c == ColDate {
+			record = append(record, frame.Dates[i])
+			continue
+		}
+		x := 0.0
+		if col := frame.Column(c); len(*col) > i {
+			x = (*col)[i]
+		}
+		record = append(record, opts.format(c, x))
+	}
+	return record
+}
+
+// WriteCSV 以逗号分隔写出frame
+func (frame *Frame) WriteCSV(w io.Writer, opts *WriteOptions) error {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	cols := opts.columns(frame)
+	wr := csv.NewWriter(w)
+	wr.UseCRLF = opts.CRLF
+	if opts.Marker {
+		wr.Write([]string{markerPrefix + " " + frame.marker().String()})
+	}
+	if !opts.NoHeader {
+		wr.Write(opts.header(cols))
+	}
+	record := make([]string, 0, len(cols))
+	for i := range frame.Dates {
+		record = opts.record(frame, cols, i, record)
+		if err := wr.Write(record); err != nil {
+			return err
+		}
+	}
+	wr.Flush()
+	return wr.Error()
+}
+
+// WriteTable 以制表符分隔写出frame, 格式同stat/doc.md
+func (frame *Frame) WriteTable(w io.Writer, opts *WriteOptions) error {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	eol := "\n"
+	if opts.CRLF {
+		eol = "\r\n"
+	}
+	cols := opts.columns(frame)
+	bw := bufio.NewWriter(w)
+	if opts.Marker {
+		bw.WriteString(markerPrefix + " " + frame.marker().String() + eol)
+	}
+	if !opts.NoHeader {
+		bw.WriteString(strings.Join(opts.header(cols), "\t") + eol)
+	}
+	record := make([]string, 0, len(cols))
+	for i := range frame.Dates {
+		record = opts.record(frame, cols, i, record)
+		bw.WriteString(strings.Join(record, "\t"))
+		bw.WriteString(eol)
+	}
+	return bw.Flush()
+}
+
+// WriteJSON 写出{"columns":[...],"record":[[date, ...], ...]}, 每条记录一行。
+// 数字写为JSON数字(NaN及±Inf写为字符串); 可由Load读回。NoHeader及Comment对JSON无效。
+func (frame *Frame) WriteJSON(w io.Writer, opts *WriteOptions) error {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+	eol := "\n"
+	if opts.CRLF {
+		eol = "\r\n"
+	}
+	cols := opts.columns(frame)
+	o := *opts
+	o.Comment = false
+	names, _ := json.Marshal(o.header(cols))
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`{`)
+	if opts.Marker {
+		bw.WriteString(`"stockstat":"` + frame.marker().String() + `",`)
+	}
+	bw.WriteString(`"columns":`)
+	bw.Write(names)
+	bw.WriteString(`,"record":[` + eol)
+	record := make([]string, 0, len(cols))
+	for i := range frame.Dates {
+		record = opts.record(frame, cols, i, record)
+		for j, v := range record {
+			if j == 0 || strings.ContainsAny(v, "NI") { // 日期及NaN, ±Inf写为字符串
+				q, _ := json.Marshal(v)
+				record[j] = string(q)
+			}
+		}
+		bw.WriteString("[" + strings.Join(record, ",") + "]")
+		if i < len(frame.Dates)-1 {
+			bw.WriteString(",")
+		}
+		bw.WriteString(eol)
+	}
+	bw.WriteString("]}" + eol)
+	return bw.Flush()
+}
@@ -0,0 +1,108 @@
+package readr
+
+import (
+	"context"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// roundTripFrame 返回有可选列的Frame: 成交额中有NaN, 没有涨跌额等列
+func roundTripFrame() *Frame {
+	frame := NewFrame([]Column{ColOpen, ColHigh, ColClose, ColLow, ColVolumn, ColAmount, ColPower}, 3)
+	t0 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	amounts := []float64{123456.5, math.NaN(), 98765.25}
+	for i := 0; i < 3; i++ {
+		t := t0.AddDate(0, 0, i)
+		frame.Append(&Bar{
+			Date: t.Format(DateLayout), Time: t,
+			Open: 10.25 + float64(i), High: 11.5 + float64(i), Close: 10.75 + float64(i), Low: 9.125 + float64(i),
+			Volumn: 1000 * float64(i+1), Amount: amounts[i], Power: 1.5,
+		})
+	}
+	return frame
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	type writer func(*Frame, io.Writer, *WriteOptions) error
+	formats := []struct {
+		name   string
+		format Format
+		write  writer
+	}{
+		{"csv", FormatCSV, (*Frame).WriteCSV},
+		{"table", FormatTable, (*Frame).WriteTable},
+		{"json", FormatIfeng, (*Frame).WriteJSON},
+	}
+	options := []struct {
+		name string
+		opts *WriteOptions
+	}{
+		{"default", nil},
+		{"precision", &WriteOptions{Precision: 3}},
+		{"digits", &WriteOptions{Digits: map[Column]int{ColVolumn: 0, ColPower: 2}}},
+		{"names", &WriteOptions{Names: map[Column]string{ColVolumn: "volumn", ColAmount: "deal money", ColPower: "power"}}},
+		{"crlf", &WriteOptions{CRLF: true, Comment: true}},
+		{"marker", &WriteOptions{Marker: true, CRLF: true}},
+		{"columns", &WriteOptions{Columns: []Column{ColDate, ColOpen, ColHigh, ColClose, ColLow, ColVolumn, ColPower}}},
+	}
+	dir := t.TempDir()
+	for _, f := range formats {
+		for _, o := range options {
+			if f.name == "table" && o.name == "names" {
+				continue // 表格以空白分隔, 列名中不能有空格
+			}
+			want := roundTripFrame()
+			path := filepath.Join(dir, f.name+"-"+o.name)
+			out, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = f.write(want, out, o.opts)
+			out.Close()
+			if err != nil {
+				t.Fatalf("%s/%s: write: %v", f.name, o.name, err)
+			}
+			got, err := Load(context.Background(), path, &Options{Format: f.format, Header: true, NoCache: true})
+			if err != nil {
+				t.Fatalf("%s/%s: Load: %v", f.name, o.name, err)
+			}
+			if o.opts != nil && o.opts.Columns != nil {
+				want.Amounts = nil
+			}
+			compareFrames(t, f.name+"/"+o.name, got, want)
+		}
+	}
+}
+
+// compareFrames 比较两个Frame的日期及各列, NaN与NaN相等
+func compareFrames(t *testing.T, name string, got, want *Frame) {
+	t.Helper()
+	if got.Len() != want.Len() {
+		t.Errorf("%s: %d rows, want %d", name, got.Len(), want.Len())
+		return
+	}
+	for i := range want.Dates {
+		if got.Dates[i] != want.Dates[i] || !got.Times[i].Equal(want.Times[i]) {
+			t.Errorf("%s: row %d date %s, want %s", name, i, got.Dates[i], want.Dates[i])
+		}
+	}
+	for c := ColOpen; int(c) < len(columnNames); c++ {
+		if got.Has(c) != want.Has(c) {
+			t.Errorf("%s: Has(%s) = %v, want %v", name, c, got.Has(c), want.Has(c))
+			continue
+		}
+		if !want.Has(c) {
+			continue
+		}
+		g, w := *got.Column(c), *want.Column(c)
+		for i := range w {
+			if g[i] != w[i] && !(math.IsNaN(g[i]) && math.IsNaN(w[i])) {
+				t.Errorf("%s: row %d %s = %v, want %v", name, i, c, g[i], w[i])
+			}
+		}
+	}
+}
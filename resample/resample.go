@@ -0,0 +1,45 @@
+// package resample 把一只股票的日K线按周, 月, 季, 年或N个交易日合并。
+package resample
+
+import (
+	"context"
+	"stockstat/calendar"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/readr"
+	"strconv"
+	"strings"
+)
+
+// Run 把股票code按period合并的K线写到out。period为week, month, quarter, year或"5d"这样的交易日数;
+// 价格先按c.Adjust复权, 各K线以其最后一个交易日为日期, 周期按数据目录的交易日历划分。
+func Run(c *config.Config, out *cli.Output, code, period string) error {
+	days, err := strconv.Atoi(strings.TrimSuffix(period, "d"))
+	var p calendar.Period
+	if err != nil {
+		if p, err = calendar.ParsePeriod(period); err != nil {
+			return cli.Usagef("%v", err)
+		}
+	} else if days < 1 {
+		return cli.Usagef("bad period %q", period)
+	}
+
+	opts := &readr.Options{Header: true, Adjust: c.Adjust}
+	frm, err := readr.Load(context.Background(), c.DataPath(code), opts)
+	if err != nil {
+		return err
+	}
+	cal, err := calendar.Open(c.DataDir)
+	if err != nil {
+		return err
+	}
+	if days > 0 {
+		frm, err = frm.ResampleDays(days, cal)
+	} else {
+		frm, err = frm.Resample(p, cal)
+	}
+	if err != nil {
+		return err
+	}
+	return out.WriteFrame(frm, nil)
+}
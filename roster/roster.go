@@ -0,0 +1,239 @@
+// package roster 读写股票列表文件stocklist.csv。
+//
+// 旧文件没有表头, 每行为"code,name"或modifyStockList写出的"code,name,updated,power"。
+// 新文件第一行是以"#v<版本>"开头的表头, 其后为列名, 如
+//
+//	#v2,code,name,exchange,board,updated,power,listed
+//	600000.SH,浦发银行,SH,main,2018-07-20,1,1999-11-10
+//
+// 按列名读取, 不认识的列被忽略, 缺少的列为零值; 交易所及板块缺少时由代码推断。
+// 代码可以是package stockcode认得的任何写法, 写出时使用规范写法。
+package roster
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"stockstat/readr"
+	"stockstat/safefile"
+	"stockstat/stockcode"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version 是Write写出的表头版本
+const Version = 2
+
+// Stock 是股票列表中的一只股票
+type Stock struct {
+	Code      stockcode.Code
+	Name      string
+	Board     stockcode.Board
+	Updated   time.Time // 数据最后更新日, 未知时为零值
+	LastPower float64   // 最后的权值, 未知时为0
+	Listed    time.Time // 上市日, 未知时为零值
+}
+
+// 各版本的列名
+var (
+	legacyColumns = []string{"code", "name", "updated", "power"}
+	columns       = []string{"code", "name", "exchange", "board", "updated", "power", "listed"}
+)
+
+// List 是股票列表
+type List []Stock
+
+// Codes 返回各股票的代码
+func (l List) Codes() []stockcode.Code {
+	codes := make([]stockcode.Code, len(l))
+	for i := range l {
+		codes[i] = l[i].Code
+	}
+	return codes
+}
+
+// Lookup 按代码查找股票, code可以是任何写法, 找不到时返回nil
+func (l List) Lookup(code string) *Stock {
+	c, err := stockcode.Parse(code)
+	if err != nil {
+		return nil
+	}
+	for i := range l {
+		if l[i].Code == c {
+			return &l[i]
+		}
+	}
+	return nil
+}
+
+// Filter 是选择股票的条件, 空字段表示不限
+type Filter struct {
+	Prefix   string // 代码数字部分的前缀, 如"600"
+	Exchange stockcode.Exchange
+	Board    stockcode.Board
+}
+
+// Match 报告s是否满足条件f
+func (f *Filter) Match(s *Stock) bool {
+	return strings.HasPrefix(s.Code.Number, f.Prefix) &&
+		(f.Exchange == "" || strings.EqualFold(string(f.Exchange), string(s.Code.Exchange))) &&
+		(f.Board == "" || strings.EqualFold(string(f.Board), string(s.Board)))
+}
+
+// Filter 返回满足条件f的股票
+func (l List) Filter(f Filter) List {
+	var res List
+	for i := range l {
+		if f.Match(&l[i]) {
+			res = append(res, l[i])
+		}
+	}
+	return res
+}
+
+// Load 读取股票列表文件path
+func Load(path string) (List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	l, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return l, nil
+}
+
+// Read 从r读取股票列表
+func Read(r io.Reader) (List, error) {
+	rd := csv.NewReader(r)
+	rd.FieldsPerRecord = -1
+	rd.TrimLeadingSpace = true
+	names := legacyColumns
+	var l List
+	for first := true; ; first = false {
+		record, err := rd.Read()
+		if err == io.EOF {
+			return l, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := rd.FieldPos(0)
+		if strings.HasPrefix(record[0], "#") {
+			if first {
+				if names, err = parseHeader(record); err != nil {
+					return nil, fmt.Errorf("line %d: %v", line, err)
+				}
+			}
+			continue
+		}
+		s, err := parseStock(names, record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		l = append(l, s)
+	}
+}
+
+// parseHeader 解析"#v<版本>,列名..."表头, 返回列名
+func parseHeader(record []string) ([]string, error) {
+	v := strings.TrimPrefix(strings.TrimSpace(record[0]), "#v")
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("bad header %q", strings.Join(record, ","))
+	}
+	if n > Version {
+		return nil, fmt.Errorf("unsupported version %d", n)
+	}
+	names := make([]string, len(record)-1)
+	for i, name := range record[1:] {
+		names[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	return names, nil
+}
+
+func parseStock(names []string, record []string) (Stock, error) {
+	var (
+		s        Stock
+		code     string
+		exchange string
+	)
+	for i, v := range record {
+		if i >= len(names) {
+			break
+		}
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		var err error
+		switch names[i] {
+		case "code":
+			code = v
+		case "name":
+			s.Name = v
+		case "exchange":
+			exchange = v
+		case "board":
+			s.Board = stockcode.Board(strings.ToLower(v))
+		case "updated":
+			s.Updated, err = readr.ParseDate(v)
+		case "power":
+			s.LastPower, err = strconv.ParseFloat(v, 64)
+		case "listed":
+			s.Listed, err = readr.ParseDate(v)
+		}
+		if err != nil {
+			return s, fmt.Errorf("%s: %v", names[i], err)
+		}
+	}
+	if code == "" {
+		return s, fmt.Errorf("missing code")
+	}
+	if exchange != "" && strings.IndexByte(code, '.') < 0 && len(code) <= 6 {
+		code += "." + exchange // 代码中没有交易所时以exchange列为准
+	}
+	var err error
+	if s.Code, err = stockcode.Parse(code); err != nil {
+		return s, err
+	}
+	if s.Board == "" {
+		s.Board = s.Code.Board()
+	}
+	return s, nil
+}
+
+// Save 把股票列表写入文件path, 写入失败时原文件不变, 原文件保留为path.bak
+func Save(path string, l List) error {
+	return safefile.WriteFile(path, func(w io.Writer) error {
+		return Write(w, l)
+	})
+}
+
+// Write 以当前版本的格式写出股票列表
+func Write(w io.Writer, l List) error {
+	wr := csv.NewWriter(w)
+	wr.UseCRLF = true
+	wr.Write(append([]string{fmt.Sprintf("#v%d", Version)}, columns...))
+	for i := range l {
+		s := &l[i]
+		power := ""
+		if s.LastPower != 0 {
+			power = strconv.FormatFloat(s.LastPower, 'f', -1, 64)
+		}
+		wr.Write([]string{s.Code.String(), s.Name, string(s.Code.Exchange), string(s.Board), formatDate(s.Updated), power, formatDate(s.Listed)})
+	}
+	wr.Flush()
+	return wr.Error()
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(readr.DateLayout)
+}
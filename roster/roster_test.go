@@ -0,0 +1,140 @@
+package roster
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := readr.ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestReadLegacy(t *testing.T) {
+	tests := []struct {
+		name, data string
+		want       List
+	}{
+		{"code,name", "600000,浦发银行\r\n1,平安银行\r\n", List{
+			{Code: stockcode.MustParse("600000.SH"), Name: "浦发银行", Board: stockcode.BoardMain},
+			{Code: stockcode.MustParse("000001.SZ"), Name: "平安银行", Board: stockcode.BoardMain},
+		}},
+		{"code,name,updated,power", "600000,浦发银行,2018-07-20,1.5\r\n300750, 宁德时代, 20240105 ,\r\n", List{
+			{Code: stockcode.MustParse("600000.SH"), Name: "浦发银行", Board: stockcode.BoardMain, Updated: day("2018-07-20"), LastPower: 1.5},
+			{Code: stockcode.MustParse("300750.SZ"), Name: "宁德时代", Board: stockcode.BoardChiNext, Updated: day("2024-01-05")},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := Read(strings.NewReader(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		data, want string
+	}{
+		{"600000,浦发银行,yesterday\r\n", "line 1: updated:"},
+		{"600000,浦发银行\r\nabc,x\r\n", "line 2: stockcode: bad code"},
+		{"#v3,code,name\r\n600000,浦发银行\r\n", "line 1: unsupported version 3"},
+		{"#vx,code\r\n", "line 1: bad header"},
+		{"#v2,name\r\n,浦发银行\r\n", "line 2: missing code"},
+	}
+	for _, tt := range tests {
+		_, err := Read(strings.NewReader(tt.data))
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("Read(%q) err = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	l := List{
+		{Code: stockcode.MustParse("600000"), Name: "浦发银行", Board: stockcode.BoardMain, Updated: day("2018-07-20"), LastPower: 1.5, Listed: day("1999-11-10")},
+		{Code: stockcode.MustParse("688981"), Name: "中芯国际", Board: stockcode.BoardStar},
+		{Code: stockcode.MustParse("830799"), Name: "艾融软件", Board: stockcode.BoardBSE, LastPower: 1},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, l); err != nil {
+		t.Fatal(err)
+	}
+	if want := "#v2,code,name,exchange,board,updated,power,listed\r\n600000.SH,浦发银行,SH,main,2018-07-20,1.5,1999-11-10\r\n"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("Write:\n%s\nwant prefix\n%s", buf.String(), want)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, l) {
+		t.Errorf("round trip:\n%+v\nwant\n%+v", got, l)
+	}
+
+	path := filepath.Join(t.TempDir(), "stocklist.csv")
+	if err := Save(path, l); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Load(path); err != nil || !reflect.DeepEqual(got, l) {
+		t.Errorf("Load after Save = %+v, %v", got, err)
+	}
+}
+
+func TestReadV2Columns(t *testing.T) {
+	// 列的顺序可以不同, 不认识的列被忽略, 没有交易所的代码以exchange列为准
+	data := "#v2,name,industry,code,exchange\r\n浦发银行,银行,600000,SH\r\n深A股,地产,200002,SZ\r\n"
+	got, err := Read(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Code.String() != "600000.SH" || got[0].Name != "浦发银行" || got[1].Code.String() != "200002.SZ" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	l, err := Read(strings.NewReader("600000,a\r\n688981,b\r\n000001,c\r\n300750,d\r\n830799,e\r\n600036,f\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		f    Filter
+		want []string
+	}{
+		{Filter{}, []string{"600000.SH", "688981.SH", "000001.SZ", "300750.SZ", "830799.BJ", "600036.SH"}},
+		{Filter{Prefix: "600"}, []string{"600000.SH", "600036.SH"}},
+		{Filter{Exchange: "sh"}, []string{"600000.SH", "688981.SH", "600036.SH"}},
+		{Filter{Exchange: stockcode.SH, Board: stockcode.BoardMain}, []string{"600000.SH", "600036.SH"}},
+		{Filter{Board: "ChiNext"}, []string{"300750.SZ"}},
+		{Filter{Board: stockcode.BoardBSE}, []string{"830799.BJ"}},
+		{Filter{Prefix: "9"}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, c := range l.Filter(tt.f).Codes() {
+			got = append(got, c.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Filter(%+v) = %v, want %v", tt.f, got, tt.want)
+		}
+	}
+	if s := l.Lookup("sz300750"); s == nil || s.Name != "d" {
+		t.Errorf("Lookup(sz300750) = %+v", s)
+	}
+	if s := l.Lookup("601398"); s != nil {
+		t.Errorf("Lookup(601398) = %+v, want nil", s)
+	}
+}
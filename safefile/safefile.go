@@ -0,0 +1,135 @@
+// package safefile 以事务方式改写文件: 先写同目录下的临时文件, fsync后原子地改名为目标文件,
+// 并把原文件保留为<path>.bak。中途出错或崩溃时目标文件保持原样, 不会被截断或只写了一半。
+//
+// 用法:
+//
+//	f, err := safefile.Create(path)
+//	if err != nil { ... }
+//	defer f.Abort()
+//	... 写入f ...
+//	return f.Commit()
+package safefile
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// BackupExt 是备份文件的扩展名
+const BackupExt = ".bak"
+
+// File 是写入中的临时文件, Commit之前目标文件不变
+type File struct {
+	*os.File
+	path string // 目标文件
+	// NoBackup 为true时Commit不保留原文件的备份
+	NoBackup bool
+	done     bool
+}
+
+// Create 在path所在目录建立临时文件, Commit时替换path
+func Create(path string) (*File, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &File{File: f, path: path}, nil
+}
+
+// Commit 把临时文件写入磁盘, 备份原文件, 再改名为目标文件
+func (f *File) Commit() error {
+	if f.done {
+		return os.ErrClosed
+	}
+	f.done = true
+	tmp := f.File.Name()
+	err := f.File.Sync()
+	if cerr := f.File.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && !f.NoBackup {
+		err = backup(f.path)
+	}
+	if err == nil {
+		err = os.Rename(tmp, f.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(filepath.Dir(f.path))
+	return nil
+}
+
+// Abort 放弃写入并删除临时文件, Commit之后调用没有作用, 可以放在defer中
+func (f *File) Abort() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	f.File.Close()
+	return os.Remove(f.File.Name())
+}
+
+// Name 返回目标文件名
+func (f *File) Name() string {
+	return f.path
+}
+
+// WriteFile 以事务方式把fn写出的内容写入path, fn出错时path不变
+func WriteFile(path string, fn func(w io.Writer) error) error {
+	f, err := Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if err := fn(f); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// backup 把path硬链接为path.bak, 不支持硬链接时复制; path不存在时什么都不做
+func backup(path string) error {
+	bak := path + BackupExt
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.Remove(bak); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(path, bak); err == nil {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return WriteFile(bak, func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+}
+
+// syncDir 把目录项的改动写入磁盘, 有的系统不支持, 忽略错误
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
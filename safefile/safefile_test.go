@@ -0,0 +1,141 @@
+package safefile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// files 返回目录dir中的文件名
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCommit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "600000.csv")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Name() != path || filepath.Dir(f.File.Name()) != dir || !strings.HasPrefix(filepath.Base(f.File.Name()), ".600000.csv.tmp") {
+		t.Errorf("temp file %s for %s", f.File.Name(), f.Name())
+	}
+	io.WriteString(f, "new")
+	if got := read(t, path); got != "old" {
+		t.Errorf("target changed before Commit: %q", got)
+	}
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, path); got != "new" {
+		t.Errorf("target %q, want new", got)
+	}
+	if got := read(t, path+BackupExt); got != "old" {
+		t.Errorf("backup %q, want old", got)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("mode %v, %v, want 0600 kept", fi.Mode(), err)
+	}
+	if names := files(t, dir); len(names) != 2 {
+		t.Errorf("files %v, want target and backup only", names)
+	}
+	if err := f.Commit(); err == nil {
+		t.Error("second Commit succeeded")
+	}
+	f.Abort() // Commit之后没有作用
+	if got := read(t, path); got != "new" {
+		t.Errorf("Abort after Commit changed target to %q", got)
+	}
+}
+
+func TestCommitNew(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "new.csv")
+	f, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.NoBackup = true
+	io.WriteString(f, "data")
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if names := files(t, dir); len(names) != 1 || names[0] != "new.csv" {
+		t.Errorf("files %v, want only new.csv", names)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "600000.csv")
+	os.WriteFile(path, []byte("old"), 0644)
+	f, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(f, "half")
+	if err := f.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, path); got != "old" {
+		t.Errorf("target %q after Abort, want old", got)
+	}
+	if names := files(t, dir); len(names) != 1 {
+		t.Errorf("files %v after Abort, want only the target", names)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "600000.csv")
+	os.WriteFile(path, []byte("old"), 0644)
+	bad := errors.New("disk full")
+	err := WriteFile(path, func(w io.Writer) error {
+		io.WriteString(w, "half")
+		return bad
+	})
+	if err != bad {
+		t.Errorf("WriteFile = %v, want %v", err, bad)
+	}
+	if got := read(t, path); got != "old" {
+		t.Errorf("target %q after failed write, want old", got)
+	}
+	if names := files(t, dir); len(names) != 1 {
+		t.Errorf("files %v after failed write, want only the target", names)
+	}
+
+	if err := WriteFile(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if read(t, path) != "new" || read(t, path+BackupExt) != "old" {
+		t.Errorf("WriteFile did not replace the file and keep the backup")
+	}
+}
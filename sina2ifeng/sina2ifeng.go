@@ -0,0 +1,114 @@
+// package sina2ifeng 把数据目录中从新浪下载的后复权股价换算为不复权的实际成交价。
+// 数据目录只保存不复权的价格及权值, 各命令读取时再按-adjust复权, 见readr.Adjustment。
+package sina2ifeng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"stockstat/config"
+	"stockstat/readr"
+	"stockstat/roster"
+	"stockstat/safefile"
+	"sync"
+	"sync/atomic"
+)
+
+//////////////////////////////////////////////////////////
+// Run
+
+// cfg 是运行设置, 见package config
+var cfg = config.Default()
+
+// Run 把股票列表中每只股票的数据文件改写为不复权的价格, 有股票失败时返回错误
+func Run(c *config.Config) error {
+	cfg = c
+	limitedThreads = cfg.Limiter()
+
+	// 从stocklist.csv读入股票代码、名称等.
+	stocks, err := roster.Load(cfg.RosterPath())
+	if err != nil {
+		return err
+	}
+
+	// 并行改写数据
+	var (
+		wg     sync.WaitGroup // 记录活动的go routines
+		failed int32
+	)
+	for _, st := range stocks {
+		wg.Add(1)
+		go func(stockCode, stockName string) {
+			defer wg.Done()
+			err := ModifyStockData(stockCode, stockName)
+			if err == ErrConverted {
+				fmt.Println(stockName, "skipped,", err)
+				return
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, stockName, err)
+				atomic.AddInt32(&failed, 1)
+				return
+			}
+			fmt.Println(stockName, "modified")
+		}(st.Code.String(), st.Name)
+	}
+	wg.Wait()
+	if failed > 0 {
+		return fmt.Errorf("%d stocks failed", failed)
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////
+// ModifyStockData
+////////////////////////////////////////////////////////
+
+var (
+	limitedThreads = cfg.Limiter()
+)
+
+// ErrConverted 表示数据文件已换算过, 被跳过
+var ErrConverted = errors.New("already converted")
+
+// ModifyStockData 把股票stockcode的后复权股价换算为不复权价格后写回数据文件。
+// 写出的文件带有标记(见readr.Marker), 已换算过的文件返回ErrConverted, 不会重复换算;
+// 旧版换算过的文件没有标记, 按其布局识别, 见readr.IsLegacy。
+// 原文件保留为<code>.csv.bak。
+func ModifyStockData(stockcode, stockname string) error {
+	limitedThreads <- struct{}{}
+	defer func() {
+		<-limitedThreads
+	}()
+
+	// 读出股票数据, 新浪的价格是后复权的
+	fname := cfg.DataPath(stockcode)
+	if m, err := readr.ReadMarker(fname); err != nil {
+		return err
+	} else if m != nil {
+		return ErrConverted
+	}
+	if legacy, err := readr.IsLegacy(fname); err != nil {
+		return err
+	} else if legacy {
+		return ErrConverted
+	}
+	frm, err := readr.Load(context.Background(), fname, &readr.Options{Header: true, NoCache: true})
+	if err != nil {
+		return err
+	}
+	if !frm.Has(readr.ColPower) {
+		return fmt.Errorf("%s: has no pow", fname)
+	}
+	frm.Adjustment = readr.AdjustBackward
+	if err := frm.Adjust(readr.AdjustNone); err != nil {
+		return fmt.Errorf("%s: %v", fname, err)
+	}
+
+	// 将改变后的数据重新写入股票数据文件, 丢弃成交额
+	return safefile.WriteFile(fname, func(w io.Writer) error {
+		return frm.WriteCSV(w, &readr.StockDataFormat)
+	})
+}
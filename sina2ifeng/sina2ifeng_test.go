@@ -0,0 +1,63 @@
+package sina2ifeng
+
+import (
+	"os"
+	"stockstat/config"
+	"stockstat/readr"
+	"strings"
+	"testing"
+)
+
+func TestModifyStockData(t *testing.T) {
+	const (
+		// 新浪下载的后复权数据, 有成交额
+		raw = "date,open,high,close,low,volume,amount,pow\r\n" +
+			"2014-01-02,20.000,22.000,21.000,19.000,1000,21000,2.000\r\n"
+		// 旧版sina2ifeng换算过的数据, 没有标记
+		legacy = "#date,open,high,cloe,low,volumn,pow\r\n" +
+			"2014-01-02,10.000,11.000,10.500,9.500,1000,2.000\r\n"
+		marked = "#stockstat v1 adjust=none\r\n" + legacy
+	)
+	tests := []struct {
+		name, data string
+		err        error
+		want       string // 换算后的第一行数据, 为空时文件不变
+	}{
+		{"raw", raw, nil, "2014-01-02,10.000,11.000,10.500,9.500,1000,2.000"},
+		{"legacy", legacy, ErrConverted, ""},
+		{"marked", marked, ErrConverted, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.Default()
+			c.DataDir = t.TempDir()
+			cfg = c
+			path := c.DataPath("600000")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := ModifyStockData("600000", "浦发银行"); err != tt.err {
+				t.Fatalf("ModifyStockData = %v, want %v", err, tt.err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if string(b) != tt.data {
+					t.Errorf("file changed:\n%s", b)
+				}
+				return
+			}
+			if m, err := readr.ReadMarker(path); err != nil || m == nil || m.Adjust != readr.AdjustNone {
+				t.Errorf("ReadMarker = %v, %v, want adjust=none", m, err)
+			}
+			if !strings.Contains(string(b), tt.want+"\r\n") {
+				t.Errorf("converted file:\n%s\nwant row %s", b, tt.want)
+			}
+			if err := ModifyStockData("600000", "浦发银行"); err != ErrConverted {
+				t.Errorf("second ModifyStockData = %v, want %v", err, ErrConverted)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// package stat 统计各股票每个权值段(两次除权之间)的涨跌幅, 见doc.md。
+package stat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"stockstat/catalog"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/events"
+	"stockstat/readr"
+	"stockstat/roster"
+	"strconv"
+)
+
+type StatResult struct {
+	StockCode            string
+	Ok                   bool // 记录是否是有效StatResult
+	Days                 []int
+	BeginDate, EndDate   []string
+	BeginPrice, EndPrice []float64
+	DeltaPrice           []float64
+	MeanDelta            []float64
+}
+
+type LastRecord struct {
+	StockCode      string
+	LastDeltaPrice float64
+	Mean           float64
+}
+
+type LastRecordSeq []LastRecord
+
+func (lrec LastRecordSeq) Len() int {
+	return len(lrec)
+}
+
+func (lrec LastRecordSeq) Less(i, j int) bool {
+	return (lrec[i].Mean < lrec[j].Mean)
+}
+
+func (lrec LastRecordSeq) Swap(i, j int) {
+	lrec[i], lrec[j] = lrec[j], lrec[i]
+}
+
+// cfg 是运行设置, 见package config
+var cfg = config.Default()
+
+// stockmap map stockcode to stockname
+var stockmap = make(map[string]string)
+
+// Header 是Run输出的表头
+var Header = []string{"StockCode", "BeginDate", "EndDate", "Days", "BeginPrice", "EndPrice", "DeltaPrice", "MeanDelta"}
+
+// Run 统计股票列表中每只股票各权值段的涨跌, 结果写到out,
+// 并将最近大跌而未反弹的股票按平均涨幅排序写到输出目录中的sort.csv。
+func Run(c *config.Config, out *cli.Output) error {
+	cfg = c
+	limitedRoutines = cfg.Limiter()
+
+	stocks, err := roster.Load(cfg.RosterPath())
+	if err != nil {
+		return err
+	}
+	for _, st := range stocks {
+		stockmap[st.Code.String()] = st.Name
+	}
+	// 有清单时跳过没有数据或过时的股票
+	codes, skipped, err := catalog.Filter(cfg.DataDir, stocks.Codes())
+	if err != nil {
+		return err
+	}
+	for code, why := range skipped {
+		log.Println(code, "skipped:", why)
+	}
+
+	// f2 for write result
+	f2, err := os.Create(cfg.OutPath("sort.csv"))
+	if err != nil {
+		return err
+	}
+	defer f2.Close()
+
+	tw, err := out.NewTable(Header)
+	if err != nil {
+		return err
+	}
+	n := 0                           // 记录gouroutine数量
+	sts := make(chan *StatResult, 0) // 统计结果
+	for _, code := range codes {
+		n++
+		go Statistic(code.String(), sts)
+	}
+	lastRecords := make([]LastRecord, 0) // 记录最后一个DelatPrice以备排序
+	for n > 0 {
+		// print result
+		res := <-sts
+		len := len(res.Days)
+		mean := 0.0
+		i := 0
+		for ; i < len; i++ {
+			mean += res.MeanDelta[i]
+			if err == nil {
+				err = tw.Write([]string{
+					res.StockCode, res.BeginDate[i], res.EndDate[i],
+					strconv.Itoa(res.Days[i]),
+					ftoa(res.BeginPrice[i]), ftoa(res.EndPrice[i]), ftoa(res.DeltaPrice[i]), ftoa(res.MeanDelta[i])})
+			}
+		}
+		if i > 8 && res.EndPrice[i-1] < 10.0 && res.DeltaPrice[i-2] < -30.0 && res.DeltaPrice[i-1] < 10.0 {
+			lastRecords = append(lastRecords, LastRecord{StockCode: res.StockCode, LastDeltaPrice: res.DeltaPrice[i-2], Mean: mean / float64(i)})
+		}
+
+		n--
+	}
+	if cerr := tw.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	sort.Sort(LastRecordSeq(lastRecords))
+	fmt.Fprintf(f2, "%s,%s,%s\r\n", "stockcode", "LastDeltaPrice", "Mean")
+	for j := len(lastRecords) - 1; j >= 0; j-- {
+		fmt.Fprintf(f2, "%s,%f,%f\r\n", lastRecords[j].StockCode, lastRecords[j].LastDeltaPrice, lastRecords[j].Mean)
+	}
+	return nil
+}
+
+func ftoa(x float64) string {
+	return strconv.FormatFloat(x, 'f', 6, 64)
+}
+
+// limitedRoutines limit the concurrent Staticstic routines.
+var limitedRoutines = cfg.Limiter()
+
+func Statistic(code string, sts chan *StatResult) {
+	limitedRoutines <- struct{}{}
+	defer func() {
+		<-limitedRoutines
+	}()
+
+	res := StatResult{StockCode: code, Ok: true}
+	defer func() {
+		sts <- &res
+	}()
+	opts := &readr.Options{Header: true, Lenient: true, Adjust: cfg.Adjust, Warn: func(e *readr.ParseError) { log.Println(e) }}
+	s, err := readr.OpenScanner(context.Background(), cfg.DataPath(code), opts)
+	if err != nil {
+		log.Println(err)
+		res.Ok = false
+		return
+	}
+	defer s.Close()
+	hasPower := s.Has(readr.ColPower) // 没有权值, 整段视为同一权值
+
+	// 逐行读取, 不保留整个文件。begin为本段第一天, prev为前一天
+	var begin, prev readr.Bar
+	j, k := 0, 0
+	for ; s.Scan(); j = j + 1 {
+		bar := s.Bar()
+		if j == 0 {
+			begin = *bar
+		} else if hasPower {
+			// k record begin index for same power
+			if events.Changed(prev.Power, bar.Power) {
+				res.addSegment(&begin, &prev, j-k)
+				begin = *bar
+				k = j
+			}
+		}
+		prev = *bar
+	}
+	if err := s.Err(); err != nil {
+		log.Println(err)
+		res.Ok = false
+		return
+	}
+	if j == 0 {
+		res.Ok = false
+		return
+	}
+	res.addSegment(&begin, &prev, j-k)
+}
+
+// addSegment 记录一段权值相同的行情, 自begin至end共days天
+func (res *StatResult) addSegment(begin, end *readr.Bar, days int) {
+	res.BeginDate = append(res.BeginDate, begin.Date)
+	res.BeginPrice = append(res.BeginPrice, begin.Close)
+	res.EndDate = append(res.EndDate, end.Date)
+	res.EndPrice = append(res.EndPrice, end.Close)
+	res.Days = append(res.Days, days)
+	deltap := (end.Close - begin.Close)
+	res.DeltaPrice = append(res.DeltaPrice, deltap/begin.Close*100.0)
+	res.MeanDelta = append(res.MeanDelta, deltap/float64(days)/begin.Close*100.0)
+}
@@ -0,0 +1,42 @@
+package stat
+
+import (
+	"os"
+	"stockstat/config"
+	"testing"
+)
+
+// segData 在2024-01-04除权(权值由1变为2), 第二行无法解析
+const segData = "#stockstat v1 adjust=none\n" +
+	"#date,open,high,close,low,volume,pow\n" +
+	"2024-01-02,10,10.5,10,9.8,1000,1\n" +
+	"2024-01-03,bad,10.5,10.2,9.8,1000,1\n" +
+	"2024-01-03,10,11.5,11,9.8,1000,1\n" +
+	"2024-01-04,5.5,6,5.5,5.2,1200,2\n" +
+	"2024-01-05,5.5,6.2,6.05,5.4,1500,2\n" +
+	"2024-01-08,6,6.7,6.6,5.9,1800,2\n"
+
+func TestStatistic(t *testing.T) {
+	cfg = config.Default()
+	cfg.DataDir = t.TempDir()
+	if err := os.WriteFile(cfg.DataPath("600000"), []byte(segData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sts := make(chan *StatResult, 1)
+	Statistic("600000", sts)
+	res := <-sts
+	if !res.Ok || len(res.Days) != 2 {
+		t.Fatalf("result %+v, want 2 segments", res)
+	}
+	if res.BeginDate[0] != "2024-01-02" || res.EndDate[0] != "2024-01-03" || res.Days[0] != 2 || res.DeltaPrice[0] != 10 {
+		t.Errorf("first segment %s..%s %d days %g%%", res.BeginDate[0], res.EndDate[0], res.Days[0], res.DeltaPrice[0])
+	}
+	if res.BeginDate[1] != "2024-01-04" || res.EndDate[1] != "2024-01-08" || res.Days[1] != 3 || res.EndPrice[1] != 6.6 {
+		t.Errorf("second segment %s..%s %d days end %g", res.BeginDate[1], res.EndDate[1], res.Days[1], res.EndPrice[1])
+	}
+
+	Statistic("600036", sts) // 没有数据文件
+	if res := <-sts; res.Ok {
+		t.Errorf("missing file: result %+v", res)
+	}
+}
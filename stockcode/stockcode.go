@@ -0,0 +1,147 @@
+// package stockcode 解析及规范化股票代码, 并由代码推断交易所及板块。
+//
+// 可以解析的写法有 600000, sh600000, SH600000, sh.600000, 600000.SH 等;
+// 规范写法为"600000.SH"。数据文件以不带交易所的6位数字命名, 见Code.Number。
+package stockcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Exchange 是交易所
+type Exchange string
+
+const (
+	SH Exchange = "SH" // 上海证券交易所
+	SZ Exchange = "SZ" // 深圳证券交易所
+	BJ Exchange = "BJ" // 北京证券交易所
+)
+
+// Board 是板块
+type Board string
+
+const (
+	BoardMain    Board = "main"    // 主板
+	BoardChiNext Board = "chinext" // 创业板, 300xxx, 301xxx
+	BoardStar    Board = "star"    // 科创板, 688xxx, 689xxx
+	BoardBSE     Board = "bse"     // 北交所
+)
+
+// Code 是股票代码
+type Code struct {
+	Number   string // 6位数字
+	Exchange Exchange
+}
+
+// numberLen 是代码数字部分的长度
+const numberLen = 6
+
+// Parse 解析股票代码s。没有写明交易所时由数字推断;
+// 不足6位的数字(如被当作整数输出后丢失了前导0的"1")在前面补0。
+func Parse(s string) (Code, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	var c Code
+	switch {
+	case len(t) > 2 && isExchange(t[:2]):
+		c.Exchange = Exchange(t[:2])
+		t = strings.TrimPrefix(t[2:], ".")
+	case len(t) > 3 && t[len(t)-3] == '.' && isExchange(t[len(t)-2:]):
+		c.Exchange = Exchange(t[len(t)-2:])
+		t = t[:len(t)-3]
+	}
+	if t == "" || len(t) > numberLen {
+		return Code{}, fmt.Errorf("stockcode: bad code %q", s)
+	}
+	for i := 0; i < len(t); i++ {
+		if !isDigit(t[i]) {
+			return Code{}, fmt.Errorf("stockcode: bad code %q", s)
+		}
+	}
+	c.Number = strings.Repeat("0", numberLen-len(t)) + t
+	if c.Exchange == "" {
+		c.Exchange = InferExchange(c.Number)
+		if c.Exchange == "" {
+			return Code{}, fmt.Errorf("stockcode: unknown exchange for %q", s)
+		}
+	}
+	return c, nil
+}
+
+// MustParse 同Parse, 出错时panic
+func MustParse(s string) Code {
+	c, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// InferExchange 由6位数字推断交易所, 无法推断时返回""
+func InferExchange(number string) Exchange {
+	if len(number) != numberLen {
+		return ""
+	}
+	switch {
+	case strings.HasPrefix(number, "92"):
+		return BJ
+	case number[0] == '6' || number[0] == '9' || number[0] == '5':
+		return SH
+	case number[0] == '0' || number[0] == '2' || number[0] == '3' || number[0] == '1':
+		return SZ
+	case number[0] == '4' || number[0] == '8':
+		return BJ
+	}
+	return ""
+}
+
+// Board 返回股票所在的板块
+func (c Code) Board() Board {
+	switch c.Exchange {
+	case SH:
+		if strings.HasPrefix(c.Number, "688") || strings.HasPrefix(c.Number, "689") {
+			return BoardStar
+		}
+	case SZ:
+		if strings.HasPrefix(c.Number, "300") || strings.HasPrefix(c.Number, "301") {
+			return BoardChiNext
+		}
+	case BJ:
+		return BoardBSE
+	}
+	return BoardMain
+}
+
+// IsZero 报告c是否为零值
+func (c Code) IsZero() bool {
+	return c.Number == ""
+}
+
+// String 返回规范写法, 如"600000.SH"
+func (c Code) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	return c.Number + "." + string(c.Exchange)
+}
+
+// Normalize 返回s的规范写法, 无法解析时原样返回
+func Normalize(s string) string {
+	c, err := Parse(s)
+	if err != nil {
+		return s
+	}
+	return c.String()
+}
+
+func isExchange(s string) bool {
+	switch Exchange(s) {
+	case SH, SZ, BJ:
+		return true
+	}
+	return false
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
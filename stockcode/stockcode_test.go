@@ -0,0 +1,62 @@
+package stockcode
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string
+		board Board
+	}{
+		{"sh600000", "600000.SH", BoardMain},
+		{"600000.SH", "600000.SH", BoardMain},
+		{"600000", "600000.SH", BoardMain},
+		{"sh.600000", "600000.SH", BoardMain},
+		{" SZ000001 ", "000001.SZ", BoardMain},
+		{"1", "000001.SZ", BoardMain},
+		{"000002.sz", "000002.SZ", BoardMain},
+		{"300750", "300750.SZ", BoardChiNext},
+		{"301236", "301236.SZ", BoardChiNext},
+		{"688981", "688981.SH", BoardStar},
+		{"689009", "689009.SH", BoardStar},
+		{"830799", "830799.BJ", BoardBSE},
+		{"430047", "430047.BJ", BoardBSE},
+		{"920002", "920002.BJ", BoardBSE},
+		{"bj872925", "872925.BJ", BoardBSE},
+	}
+	for _, tt := range tests {
+		c, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if c.String() != tt.want || c.Board() != tt.board {
+			t.Errorf("Parse(%q) = %s %s, want %s %s", tt.in, c, c.Board(), tt.want, tt.board)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{"", "sh", "6000001", "60000a", "600000.HK", "hk600000", "600000.", "-1", "700000"} {
+		if c, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %s, want error", in, c)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"sz000001", "000001.SZ"},
+		{"6.txt", "6.txt"},
+		{"stocklist", "stocklist"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	var zero Code
+	if !zero.IsZero() || zero.String() != "" {
+		t.Errorf("zero Code = %q", zero)
+	}
+}
@@ -0,0 +1,328 @@
+// stockstat 是各统计及数据整理工具的统一入口:
+//
+//	stockstat <command> [flags] [args]
+//
+// 每个子命令都接受-config, -data, -roster, -outdir, -workers, -adjust, -provider, -source(见package config),
+// 输出表格的子命令还接受--format csv|json|table及--out。
+// 运行"stockstat help <command>"查看子命令的用法。
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"stockstat/bincache"
+	"stockstat/calendar"
+	"stockstat/catalog"
+	"stockstat/cli"
+	"stockstat/corr"
+	"stockstat/csv2table"
+	"stockstat/events"
+	"stockstat/howdist"
+	"stockstat/modifyStockList"
+	"stockstat/panel"
+	"stockstat/readr"
+	"stockstat/resample"
+	"stockstat/sina2ifeng"
+	"stockstat/stat"
+	"stockstat/update"
+	"stockstat/validate"
+)
+
+var compress bool // cache -z
+
+var period string // resample -period
+
+// update的参数
+var (
+	updateOpts     = update.DefaultOptions
+	conflictPolicy string
+)
+
+// align的参数
+var (
+	joinHow    string
+	fillPolicy string
+	column     string
+	crossOp    string
+)
+
+// infer的参数
+var (
+	minConfidence float64
+	applyFile     string
+)
+
+// calendar的参数
+var (
+	inferDays    bool
+	listHolidays bool
+)
+
+// validate的参数
+var (
+	checkOpts   validate.Options
+	reportKind  string
+	minSeverity string
+)
+
+var commands = []*cli.Command{
+	{
+		Name:    "stat",
+		Short:   "statistics of price change between ex-rights days",
+		MinArgs: 0, MaxArgs: 0,
+		Output: true,
+		Long:   "Stocks that dropped recently and did not rebound are also written to sort.csv in the output directory.",
+		Run: func(env *cli.Env) error {
+			return stat.Run(env.Config, env.Out)
+		},
+	},
+	{
+		Name:    "corr",
+		Short:   "find the most correlated stock for each stock",
+		MinArgs: 0, MaxArgs: 0,
+		Output:     true,
+		DefaultOut: corr.ResultFileName,
+		Run: func(env *cli.Env) error {
+			return corr.Run(env.Config, env.Out)
+		},
+	},
+	{
+		Name:    "dist",
+		Args:    "code",
+		Short:   "distribution of daily price change of a stock",
+		MinArgs: 1, MaxArgs: 1,
+		Output: true,
+		Run: func(env *cli.Env) error {
+			return howdist.Run(env.Config, env.Out, env.Args[0])
+		},
+	},
+	{
+		Name:    "events",
+		Args:    "[code...]",
+		Short:   "list ex-rights and ex-dividend days found from the pow column",
+		MinArgs: 0, MaxArgs: -1,
+		Output: true,
+		Long: "Without codes, all stocks in the roster are scanned. A day whose pow changed by more than 0.5%\n" +
+			"is an event; a ratio of at least 1.1 is classed as bonus shares or a split, a smaller one as a\n" +
+			"cash dividend. The events of the scanned stocks are also saved to " + events.FileName + " in the data directory.",
+		Run: func(env *cli.Env) error {
+			return events.Run(env.Config, env.Out, env.Args)
+		},
+	},
+	{
+		Name:    "infer",
+		Args:    "[code...]",
+		Short:   "propose pow for data files whose pow is missing or constant, or write reviewed proposals back",
+		MinArgs: 0, MaxArgs: -1,
+		Output: true,
+		Long: "Days whose low is below the price limit are matched against common bonus share and split ratios\n" +
+			"(10:1 to 10:50). Each proposal has a confidence from 0 to 1; those at least -min-confidence are\n" +
+			"marked accept=yes. Save the proposals with -out, review the csv, edit accept or ratio, then run\n" +
+			"infer -apply <file> to rebuild the pow column of those data files. Cash dividends cannot be inferred.",
+		Flags: func(fs *flag.FlagSet) {
+			fs.Float64Var(&minConfidence, "min-confidence", 0.5, "lowest confidence marked accept=yes")
+			fs.StringVar(&applyFile, "apply", "", "write the accepted rows of this reviewed csv to the data files")
+		},
+		Run: func(env *cli.Env) error {
+			if applyFile == "" {
+				return events.RunInfer(env.Config, env.Out, env.Args, minConfidence)
+			}
+			if len(env.Args) > 0 {
+				return cli.Usagef("codes cannot be given with -apply")
+			}
+			return events.RunApply(env.Config, env.Out, applyFile)
+		},
+	},
+	{
+		Name:    "adjust",
+		Args:    "code",
+		Short:   "print prices of a stock adjusted by -adjust",
+		MinArgs: 1, MaxArgs: 1,
+		Output: true,
+		Long:   "The data file is not changed.",
+		Run: func(env *cli.Env) error {
+			opts := &readr.Options{Header: true, Adjust: env.Config.Adjust}
+			frm, err := readr.Load(context.Background(), env.Config.DataPath(env.Args[0]), opts)
+			if err != nil {
+				return err
+			}
+			return env.Out.WriteFrame(frm, nil)
+		},
+	},
+	{
+		Name:    "resample",
+		Args:    "code",
+		Short:   "print weekly, monthly, quarterly, yearly or N-day bars of a stock",
+		MinArgs: 1, MaxArgs: 1,
+		Output: true,
+		Long: "Prices are adjusted by -adjust first. Each bar is dated by its last trading day,\n" +
+			"and periods follow the trading calendar (see the calendar command).",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&period, "period", "week", "period: week, month, quarter, year or a number of trading days such as 5d")
+		},
+		Run: func(env *cli.Env) error {
+			return resample.Run(env.Config, env.Out, env.Args[0], period)
+		},
+	},
+	{
+		Name:    "align",
+		Args:    "[code...]",
+		Short:   "print a column of several stocks side by side on common dates",
+		MinArgs: 0, MaxArgs: -1,
+		Output: true,
+		Long: "Without codes, all usable stocks in the roster are printed. Prices are adjusted by -adjust.\n" +
+			"With -op, each day's values across the stocks are ranked, standardized or demeaned.\n" +
+			"Missing values are printed empty.",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&joinHow, "join", "inner", "dates kept: inner, outer or left (dates of the first stock)")
+			fs.StringVar(&fillPolicy, "fill", "nan", "missing days: nan, forward or suspended")
+			fs.StringVar(&column, "column", "close", "column printed")
+			fs.StringVar(&crossOp, "op", panel.OpNone, "cross-sectional op: none, rank, zscore or demean")
+		},
+		Run: func(env *cli.Env) error {
+			opts := &panel.Options{}
+			var err error
+			if opts.Join, err = readr.ParseJoin(joinHow); err != nil {
+				return &cli.UsageError{Msg: err.Error()}
+			}
+			if opts.Fill, err = readr.ParseFill(fillPolicy); err != nil {
+				return &cli.UsageError{Msg: err.Error()}
+			}
+			col := readr.LookupColumn(column)
+			if col == readr.ColSkip || col == readr.ColDate {
+				return cli.Usagef("unknown column %q", column)
+			}
+			opts.Fields = []readr.Column{col}
+			return panel.Run(env.Config, env.Out, env.Args, opts, crossOp)
+		},
+	},
+	{
+		Name:    "sina",
+		Short:   "convert backward adjusted prices downloaded from sina to raw prices, in place",
+		MinArgs: 0, MaxArgs: 0,
+		Run: func(env *cli.Env) error {
+			return sina2ifeng.Run(env.Config)
+		},
+	},
+	{
+		Name:    "update",
+		Short:   "download new daily bars of the stocks in the roster and merge them into the data files",
+		MinArgs: 0, MaxArgs: 0,
+		Output: true,
+		Long: "Bars after each stock's last update day are requested from -provider and the last update\n" +
+			"day and pow are written back to the roster. Exits with status 1 if any stock failed.\n" +
+			"Finished stocks are kept in " + update.JournalName + " in the data directory, so an interrupted\n" +
+			"or failed update run again only fetches the rest; -restart ignores it.\n" +
+			"The last day on file is fetched again to catch revised values and a changed pow base;\n" +
+			"values that differ are handled by -on-conflict and listed in " + update.ConflictFile + " in the output directory.\n" +
+			"Providers without pow (ifeng) carry the last pow forward; new days whose prices break the limit band\n" +
+			"are listed under suspects as possible splits, to be checked against a provider with pow.",
+		Flags: func(fs *flag.FlagSet) {
+			fs.Float64Var(&updateOpts.Rate, "rate", updateOpts.Rate, "max requests per second, 0 for no limit")
+			fs.IntVar(&updateOpts.Burst, "burst", updateOpts.Burst, "max requests sent back to back before -rate applies")
+			fs.IntVar(&updateOpts.PerHost, "per-host", updateOpts.PerHost, "max concurrent requests to one host, 0 for no limit")
+			fs.IntVar(&updateOpts.Retries, "retries", updateOpts.Retries, "retries of a request after network errors, 429 or 5xx")
+			fs.BoolVar(&updateOpts.Restart, "restart", false, "update all stocks, ignoring an unfinished update")
+			fs.StringVar(&conflictPolicy, "on-conflict", "replace", "values differing from the data file: replace, keep or reject (fail the stock)")
+		},
+		Run: func(env *cli.Env) error {
+			policy, err := readr.ParsePolicy(conflictPolicy)
+			if err != nil {
+				return &cli.UsageError{Msg: err.Error()}
+			}
+			updateOpts.Policy = policy
+			return update.Run(env.Config, env.Out, &updateOpts)
+		},
+	},
+	{
+		Name:    "roster",
+		Short:   "add last update day and power to the stock roster",
+		MinArgs: 0, MaxArgs: 0,
+		Run: func(env *cli.Env) error {
+			return modifystocklist.Run(env.Config)
+		},
+	},
+	{
+		Name:    "convert",
+		Args:    "[dir]",
+		Short:   "convert table files in dir (default data directory) to csv",
+		MinArgs: 0, MaxArgs: 1,
+		Run: func(env *cli.Env) error {
+			return csv2table.Run(dirArg(env))
+		},
+	},
+	{
+		Name:    "catalog",
+		Args:    "[dir]",
+		Short:   "scan dir (default data directory) and write its manifest",
+		MinArgs: 0, MaxArgs: 1,
+		Output: true,
+		Long: "The manifest is written to " + catalog.ManifestName + " in dir; stat and corr use it\n" +
+			"to skip stocks whose data file is empty or stale. The entries are also written to --out.",
+		Run: func(env *cli.Env) error {
+			return catalog.Run(env.Config, env.Out, dirArg(env))
+		},
+	},
+	{
+		Name:    "validate",
+		Short:   "check data files for bad rows and write a quality report",
+		MinArgs: 0, MaxArgs: 0,
+		Output: true,
+		Long:   "Exits with status 1 if any stock has an error level issue.",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&reportKind, "report", validate.ReportStocks, "report: issues, stocks or market")
+			fs.StringVar(&minSeverity, "level", "info", "minimum severity reported: info, warning or error")
+			fs.Float64Var(&checkOpts.MaxJump, "max-jump", validate.DefaultMaxJump, "max close change without pow change, 0.35 = 35%")
+			fs.IntVar(&checkOpts.MaxGap, "max-gap", validate.DefaultMaxGap, "max trading days missing between two rows")
+		},
+		Run: func(env *cli.Env) error {
+			min, err := validate.ParseSeverity(minSeverity)
+			if err != nil {
+				return &cli.UsageError{Msg: err.Error()}
+			}
+			return validate.Run(env.Config, env.Out, &checkOpts, reportKind, min)
+		},
+	},
+	{
+		Name:    "calendar",
+		Args:    "[from [to]]",
+		Short:   "list trading days from from to to (default this year)",
+		MinArgs: 0, MaxArgs: 2,
+		Output: true,
+		Long: "Holidays are read from the bundled list and " + calendar.HolidayFile + " in the data directory.\n" +
+			"With -infer, the dates found in the data files are taken as the trading days of the range they cover.",
+		Flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&inferDays, "infer", false, "infer trading days from the data files")
+			fs.BoolVar(&listHolidays, "holidays", false, "list weekdays the market is closed instead")
+		},
+		Run: func(env *cli.Env) error {
+			return catalog.RunCalendar(env.Config, env.Out, env.Args, inferDays, listHolidays)
+		},
+	},
+	{
+		Name:    "cache",
+		Args:    "[dir]",
+		Short:   "update binary caches of csv files in dir (default data directory)",
+		MinArgs: 0, MaxArgs: 1,
+		Flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&compress, "z", false, "compress the cache files")
+		},
+		Run: func(env *cli.Env) error {
+			return bincache.Run(dirArg(env), compress)
+		},
+	},
+}
+
+// dirArg 返回参数中的目录, 没有时返回数据目录
+func dirArg(env *cli.Env) string {
+	if len(env.Args) > 0 {
+		return env.Args[0]
+	}
+	return env.Config.DataDir
+}
+
+func main() {
+	app := &cli.App{Name: "stockstat", Commands: commands}
+	os.Exit(app.Main(os.Args[1:]))
+}
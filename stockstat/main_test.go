@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/readr"
+	"strings"
+	"testing"
+	"time"
+)
+
+// run 以args运行stockstat, 返回退出码及标准输出, 标准错误
+func run(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	app := &cli.App{Name: "stockstat", Commands: commands, Stdout: &stdout, Stderr: &stderr}
+	code := app.Main(args)
+	return code, stdout.String(), stderr.String()
+}
+
+// testDir 返回有600000及600036两只股票的数据目录, 数据为2024-01-02起的10个交易日
+func testDir(t *testing.T) string {
+	t.Helper()
+	for _, env := range []string{config.EnvConfig, config.EnvDataDir, config.EnvRoster, config.EnvOutDir,
+		config.EnvWorkers, config.EnvAdjust, config.EnvProvider, config.EnvSource} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	for k, code := range []string{"600000", "600036"} {
+		frame := readr.NewFrame([]readr.Column{readr.ColOpen, readr.ColHigh, readr.ColClose, readr.ColLow, readr.ColVolumn, readr.ColPower}, 10)
+		for d := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC); frame.Len() < 10; d = d.AddDate(0, 0, 1) {
+			if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+				continue
+			}
+			p := float64(10*(k+1) + frame.Len())
+			frame.Append(&readr.Bar{Date: d.Format(readr.DateLayout), Time: d, Open: p, High: p + 1, Close: p, Low: p - 1, Volumn: 100, Power: 1})
+		}
+		f, err := os.Create(filepath.Join(dir, code+".csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = frame.WriteCSV(f, &readr.StockDataFormat)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, config.DefaultRoster), []byte("600000,浦发银行\r\n600036,招商银行\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestExitCodes(t *testing.T) {
+	dir := testDir(t)
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, cli.ExitUsage},
+		{[]string{"nosuch"}, cli.ExitUsage},
+		{[]string{"help", "nosuch"}, cli.ExitUsage},
+		{[]string{"help"}, cli.ExitOK},
+		{[]string{"dist"}, cli.ExitUsage},                        // 缺少参数
+		{[]string{"adjust", "600000", "600036"}, cli.ExitUsage},  // 参数过多
+		{[]string{"stat", "-nosuch"}, cli.ExitUsage},             // 未知参数
+		{[]string{"calendar", "--format", "xml"}, cli.ExitUsage}, // 未知格式
+		{[]string{"calendar", "-workers", "0"}, cli.ExitUsage},   // 设置有误
+		{[]string{"calendar", "2024-13-01"}, cli.ExitUsage},      // 子命令报告的参数错误
+		{[]string{"resample", "-data", dir, "-period", "fortnight", "600000"}, cli.ExitUsage},
+		{[]string{"align", "-data", dir, "-op", "sort", "600000"}, cli.ExitUsage},
+		{[]string{"align", "-data", dir, "-column", "colour", "600000"}, cli.ExitUsage},
+		{[]string{"adjust", "-data", dir, "000001"}, cli.ExitError}, // 没有数据文件
+		{[]string{"adjust", "-data", dir, "600000"}, cli.ExitOK},
+		{[]string{"validate", "-data", dir}, cli.ExitOK},
+	}
+	for _, tt := range tests {
+		if got, _, stderr := run(t, tt.args...); got != tt.want {
+			t.Errorf("stockstat %s: exit %d, want %d\n%s", strings.Join(tt.args, " "), got, tt.want, stderr)
+		}
+	}
+}
+
+func TestHelp(t *testing.T) {
+	testDir(t)
+	for _, c := range commands {
+		code, stdout, stderr := run(t, c.Name, "--help")
+		if code != cli.ExitOK {
+			t.Errorf("%s --help: exit %d", c.Name, code)
+		}
+		usage := stdout + stderr
+		if !strings.HasPrefix(usage, "usage: stockstat "+c.Name+" ") || !strings.Contains(usage, c.Short) || !strings.Contains(usage, "-data") {
+			t.Errorf("%s --help:\n%s", c.Name, usage)
+		}
+		if strings.Contains(usage, "-format") != c.Output {
+			t.Errorf("%s --help: -format listed = %v, want %v", c.Name, !c.Output, c.Output)
+		}
+		if _, stdout, _ := run(t, "help", c.Name); stdout != usage {
+			t.Errorf("help %s differs from %s --help", c.Name, c.Name)
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	dir := testDir(t)
+	args := []string{"calendar", "-data", dir, "--format"}
+	dates := []string{"2024-02-05", "2024-02-08"} // 2024-02-09起春节休市
+
+	_, stdout, stderr := run(t, append(args, append([]string{cli.FormatCSV}, dates...)...)...)
+	if want := "date,weekday\n2024-02-05,Mon\n2024-02-06,Tue\n2024-02-07,Wed\n2024-02-08,Thu\n"; stdout != want {
+		t.Errorf("csv:\n%s\nwant\n%s%s", stdout, want, stderr)
+	}
+
+	_, stdout, _ = run(t, append(args, append([]string{cli.FormatJSON}, dates...)...)...)
+	var rows []map[string]string
+	if err := json.Unmarshal([]byte(stdout), &rows); err != nil {
+		t.Fatalf("json: %v\n%s", err, stdout)
+	}
+	if len(rows) != 4 || rows[0]["date"] != "2024-02-05" || rows[3]["weekday"] != "Thu" {
+		t.Errorf("json: %v", rows)
+	}
+
+	_, stdout, _ = run(t, append(args, append([]string{cli.FormatTable}, dates...)...)...)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 5 || strings.Fields(lines[0])[0] != "date" || strings.Join(strings.Fields(lines[4]), " ") != "2024-02-08 Thu" {
+		t.Errorf("table:\n%s", stdout)
+	}
+
+	// 节假日, 输出到文件
+	out := filepath.Join(t.TempDir(), "holidays.csv")
+	if code, _, stderr := run(t, "calendar", "-data", dir, "-holidays", "--out", out, "2024-02-05", "2024-02-18"); code != cli.ExitOK {
+		t.Fatalf("calendar -holidays: exit %d\n%s", code, stderr)
+	}
+	if b, _ := os.ReadFile(out); !strings.HasPrefix(string(b), "date,weekday\n2024-02-09,Fri\n2024-02-12,Mon\n") {
+		t.Errorf("holidays:\n%s", b)
+	}
+}
+
+func TestResampleAlign(t *testing.T) {
+	dir := testDir(t)
+	code, stdout, stderr := run(t, "resample", "-data", dir, "-period", "5d", "600000")
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if code != cli.ExitOK || len(lines) != 3 || !strings.HasPrefix(lines[1], "2024-01-08,") || !strings.HasPrefix(lines[2], "2024-01-15,") {
+		t.Errorf("resample: exit %d\n%s%s", code, stdout, stderr)
+	}
+
+	code, stdout, stderr = run(t, "align", "-data", dir, "-column", "close")
+	lines = strings.Split(strings.TrimSpace(stdout), "\n")
+	if code != cli.ExitOK || len(lines) != 11 || lines[0] != "date,600000.SH,600036.SH" || lines[1] != "2024-01-02,10,20" {
+		t.Errorf("align: exit %d\n%s%s", code, stdout, stderr)
+	}
+
+	code, stdout, _ = run(t, "align", "-data", dir, "-op", "rank", "--format", "json", "600036", "600000")
+	var rows []map[string]string
+	if err := json.Unmarshal([]byte(stdout), &rows); err != nil || code != cli.ExitOK {
+		t.Fatalf("align -op rank: exit %d %v\n%s", code, err, stdout)
+	}
+	if len(rows) != 10 || rows[0]["600036.SH"] != "2" || rows[0]["600000.SH"] != "1" {
+		t.Errorf("align -op rank: %v", rows[0])
+	}
+}
@@ -0,0 +1,192 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Fetcher 为各行情源下载页面: 用令牌桶限制请求速率, 限制对同一主机的并发请求数,
+// 网络错误、429及5xx时按指数退避(带随机抖动)重试。同一个Fetcher可以由多个goroutine共用。
+type Fetcher struct {
+	Client   *http.Client  // 为nil时使用DefaultClient
+	Rate     float64       // 每秒请求数, 0表示不限
+	Burst    int           // 可以连续发出的请求数, 小于1时为1
+	PerHost  int           // 对同一主机的最大并发请求数, 0表示不限
+	Retries  int           // 失败后最多重试的次数
+	MinDelay time.Duration // 第一次重试前的等待, 之后每次加倍
+	MaxDelay time.Duration // 最长的等待, 0表示不限
+
+	mu     sync.Mutex
+	tokens float64   // 令牌数, 为负表示已预约的请求
+	last   time.Time // 上次计算tokens的时间
+	hosts  map[string]chan struct{}
+}
+
+// DefaultClient 是Fetcher缺省使用的http.Client
+var DefaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// DefaultFetcher 是行情源没有指定Fetcher时使用的, 不限速也不重试
+var DefaultFetcher = &Fetcher{}
+
+// retryable 是可以重试的错误, after为服务器要求的等待(Retry-After)
+type retryable struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryable) Error() string { return e.err.Error() }
+
+// Get 下载url, 调用parse解析响应。parse的错误不重试。
+func (f *Fetcher) Get(ctx context.Context, url string, parse func(io.Reader) error) error {
+	for attempt := 0; ; attempt++ {
+		err := f.try(ctx, url, parse)
+		var re *retryable
+		if !errors.As(err, &re) {
+			return err
+		}
+		if attempt >= f.Retries || ctx.Err() != nil {
+			return re.err
+		}
+		timer := time.NewTimer(f.backoff(attempt, re.after))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// try 请求一次url
+func (f *Fetcher) try(ctx context.Context, rawurl string, parse func(io.Reader) error) error {
+	if err := f.wait(ctx); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodGet, rawurl, nil)
+	if err != nil {
+		return err
+	}
+	release, err := f.acquire(ctx, req.URL.Host)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	client := f.Client
+	if client == nil {
+		client = DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &retryable{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("%s: %s", rawurl, resp.Status)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return &retryable{err: err, after: retryAfter(resp.Header.Get("Retry-After"))}
+		}
+		return err
+	}
+	if err := parse(resp.Body); err != nil {
+		return fmt.Errorf("%s: %v", rawurl, err)
+	}
+	return nil
+}
+
+// retryAfter 解析Retry-After中的秒数, 不支持HTTP日期
+func retryAfter(s string) time.Duration {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+// backoff 返回第attempt次(从0开始)重试前的等待: MinDelay*2^attempt, 不超过MaxDelay,
+// 再随机取其一半至全部, 以免各请求同时重试; 服务器要求更长的等待时以服务器为准。
+func (f *Fetcher) backoff(attempt int, after time.Duration) time.Duration {
+	d := f.MinDelay
+	for i := 0; i < attempt && (f.MaxDelay <= 0 || d < f.MaxDelay); i++ {
+		d *= 2
+	}
+	if f.MaxDelay > 0 && d > f.MaxDelay {
+		d = f.MaxDelay
+	}
+	if d > 1 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	if after > d {
+		d = after
+	}
+	return d
+}
+
+// wait 从令牌桶取一个令牌, 没有时等待
+func (f *Fetcher) wait(ctx context.Context) error {
+	if f.Rate <= 0 {
+		return nil
+	}
+	burst := float64(f.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	f.mu.Lock()
+	now := time.Now()
+	if f.last.IsZero() {
+		f.tokens = burst
+	} else if f.tokens += now.Sub(f.last).Seconds() * f.Rate; f.tokens > burst {
+		f.tokens = burst
+	}
+	f.last = now
+	f.tokens--
+	d := time.Duration(0)
+	if f.tokens < 0 {
+		d = time.Duration(-f.tokens / f.Rate * float64(time.Second))
+	}
+	f.mu.Unlock()
+	if d == 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// acquire 占用主机host的一个并发名额, 返回释放名额的函数
+func (f *Fetcher) acquire(ctx context.Context, host string) (func(), error) {
+	if f.PerHost <= 0 {
+		return func() {}, nil
+	}
+	f.mu.Lock()
+	if f.hosts == nil {
+		f.hosts = make(map[string]chan struct{})
+	}
+	sem, ok := f.hosts[host]
+	if !ok {
+		sem = make(chan struct{}, f.PerHost)
+		f.hosts[host] = sem
+	}
+	f.mu.Unlock()
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,169 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statusServer 依次以codes应答各请求, codes用完后应答200, 返回请求数
+func statusServer(t *testing.T, codes ...int) (*httptest.Server, *int32) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(&n, 1)) - 1
+		if i < len(codes) && codes[i] != http.StatusOK {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(codes[i])
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &n
+}
+
+func discard(r io.Reader) error {
+	_, err := io.Copy(io.Discard, r)
+	return err
+}
+
+func TestFetcherRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		codes    []int
+		retries  int
+		requests int32
+		err      string // 错误信息的一部分, 为空表示成功
+	}{
+		{"429 then ok", []int{429}, 3, 2, ""},
+		{"5xx then ok", []int{502, 503}, 3, 3, ""},
+		{"give up", []int{503, 503, 503, 503}, 2, 3, "503"},
+		{"no retries", []int{429}, 0, 1, "429"},
+		{"not retryable", []int{404, 404}, 3, 1, "404"},
+	}
+	for _, tt := range tests {
+		ts, n := statusServer(t, tt.codes...)
+		f := &Fetcher{Retries: tt.retries, MinDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+		err := f.Get(context.Background(), ts.URL, discard)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: err %v, want %q", tt.name, err, tt.err)
+		}
+		if *n != tt.requests {
+			t.Errorf("%s: %d requests, want %d", tt.name, *n, tt.requests)
+		}
+	}
+}
+
+func TestFetcherParseErrorNotRetried(t *testing.T) {
+	ts, n := statusServer(t)
+	f := &Fetcher{Retries: 3, MinDelay: time.Millisecond}
+	bad := errors.New("bad page")
+	if err := f.Get(context.Background(), ts.URL, func(io.Reader) error { return bad }); err == nil || !strings.Contains(err.Error(), "bad page") {
+		t.Errorf("err %v, want bad page", err)
+	}
+	if *n != 1 {
+		t.Errorf("%d requests, want 1", *n)
+	}
+}
+
+func TestFetcherCancelDuringBackoff(t *testing.T) {
+	ts, n := statusServer(t, 500, 500)
+	f := &Fetcher{Retries: 5, MinDelay: time.Minute}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	err := f.Get(ctx, ts.URL, discard)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Get returned %v after cancel", d)
+	}
+	if *n != 1 {
+		t.Errorf("%d requests, want 1", *n)
+	}
+}
+
+func TestFetcherBackoff(t *testing.T) {
+	f := &Fetcher{MinDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		for k := 0; k < 20; k++ {
+			if d := f.backoff(attempt, 0); d < max/2 || d > max {
+				t.Errorf("backoff(%d) = %v, want in [%v, %v]", attempt, d, max/2, max)
+			}
+		}
+	}
+	// 服务器要求更长的等待时以服务器为准
+	if d := f.backoff(0, 3*time.Second); d != 3*time.Second {
+		t.Errorf("backoff with Retry-After 3s = %v", d)
+	}
+	for _, tt := range []struct {
+		header string
+		want   time.Duration
+	}{{"2", 2 * time.Second}, {"", 0}, {"-1", 0}, {"Wed, 21 Oct 2015 07:28:00 GMT", 0}} {
+		if got := retryAfter(tt.header); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestFetcherPerHost(t *testing.T) {
+	var cur, max int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&cur, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		atomic.AddInt32(&cur, -1)
+	}))
+	defer ts.Close()
+
+	f := &Fetcher{PerHost: 2}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f.Get(context.Background(), ts.URL, discard); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if max != 2 {
+		t.Errorf("max concurrent requests %d, want 2", max)
+	}
+}
+
+func TestFetcherRate(t *testing.T) {
+	ts, n := statusServer(t)
+	f := &Fetcher{Rate: 20, Burst: 2}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := f.Get(context.Background(), ts.URL, discard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 前两个请求立即发出, 之后每50ms一个
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("4 requests at 20/s with burst 2 took %v, want at least 100ms", d)
+	}
+	if *n != 4 {
+		t.Errorf("%d requests, want 4", *n)
+	}
+}
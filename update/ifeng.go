@@ -0,0 +1,78 @@
+package update
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"strings"
+	"time"
+)
+
+// DefaultIfengURL 是凤凰网日K线的缺省地址
+const DefaultIfengURL = "http://api.finance.ifeng.com/akdaily/"
+
+// IfengSource 从BaseURL下载凤凰网日K线JSON(见readr.ScanIfengJSON)。
+// 请求为 BaseURL?code=sh600000&type=last&begin=2024-01-02,
+// type=last表示不复权的价格, begin为所需的第一天, 省略时下载全部历史。
+// 服务器可以忽略begin, 多出的数据被去掉。
+// 凤凰网的成交量以手(100股)为单位, 没有成交额及复权因子。
+type IfengSource struct {
+	BaseURL string
+	Fetcher *Fetcher // 为nil时使用DefaultFetcher
+}
+
+// URL 返回下载股票code自since起的数据的地址
+func (src *IfengSource) URL(code stockcode.Code, since time.Time) string {
+	q := url.Values{}
+	q.Set("code", strings.ToLower(string(code.Exchange))+code.Number)
+	q.Set("type", "last")
+	if !since.IsZero() {
+		q.Set("begin", since.Format(readr.DateLayout))
+	}
+	sep := "?"
+	if strings.Contains(src.BaseURL, "?") {
+		sep = "&"
+	}
+	return src.BaseURL + sep + q.Encode()
+}
+
+// Fetch 实现Source
+func (src *IfengSource) Fetch(ctx context.Context, code stockcode.Code, since time.Time) (*readr.Frame, error) {
+	var bars []readr.Bar
+	err := fetcherOf(src.Fetcher).Get(ctx, src.URL(code, since), func(r io.Reader) (err error) {
+		bars, err = src.parse(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return frameOf(ifengColumns, bars, since), nil
+}
+
+// Parse 实现Source
+func (src *IfengSource) Parse(r io.Reader) (*readr.Frame, error) {
+	bars, err := src.parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return frameOf(ifengColumns, bars, time.Time{}), nil
+}
+
+// ifengColumns 是凤凰网数据换算后有的列
+var ifengColumns = []readr.Column{readr.ColOpen, readr.ColHigh, readr.ColClose, readr.ColLow, readr.ColVolumn}
+
+func (src *IfengSource) parse(r io.Reader) ([]readr.Bar, error) {
+	s, err := readr.NewScanner(context.Background(), r, "", &readr.Options{Format: readr.FormatIfeng})
+	if err != nil {
+		return nil, err
+	}
+	var bars []readr.Bar
+	for s.Scan() {
+		bar := *s.Bar()
+		bar.Volumn *= 100 // 手 -> 股
+		bars = append(bars, bar)
+	}
+	return bars, s.Err()
+}
@@ -0,0 +1,134 @@
+package update
+
+import (
+	"fmt"
+	"os"
+	"stockstat/readr"
+	"stockstat/roster"
+	"stockstat/stockcode"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JournalName 是数据目录中更新日志的文件名
+const JournalName = "update.journal"
+
+// Journal 记录一次更新中已完成的股票, 使中断的更新可以从中断处继续。
+//
+// 第一行为"#v1,<行情源>", 其后每完成一只股票追加一行"code,updated,power"并立即写入磁盘,
+// 中断时写了一半的最后一行被忽略。更新全部成功后删除日志。
+type Journal struct {
+	path string
+	Done map[stockcode.Code]roster.Stock // 日志中已完成的股票, 只有Updated及LastPower
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// OpenJournal 打开更新日志path, 读取其中已完成的股票。
+// restart为true、文件不存在、文件不能解析或不是由行情源provider写的时, 新建空日志。
+func OpenJournal(path, provider string, restart bool) (*Journal, error) {
+	j := &Journal{path: path, Done: map[stockcode.Code]roster.Stock{}}
+	if !restart {
+		if err := j.read(provider); err != nil {
+			j.Done = map[stockcode.Code]roster.Stock{}
+		} else {
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, err
+			}
+			j.f = f
+			return j, nil
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	j.f = f
+	if _, err := fmt.Fprintf(f, "#v1,%s\n", provider); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return j, f.Sync()
+}
+
+// read 读取已有的日志
+func (j *Journal) read(provider string) error {
+	b, err := os.ReadFile(j.path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(b), "\n")
+	if lines[0] != "#v1,"+provider {
+		return fmt.Errorf("%s: not a journal of %s", j.path, provider)
+	}
+	// 最后一个元素是最后的换行之后的内容, 为空或写了一半的行
+	for _, line := range lines[1 : len(lines)-1] {
+		f := strings.Split(line, ",")
+		if len(f) != 3 {
+			return fmt.Errorf("%s: bad line %q", j.path, line)
+		}
+		code, err := stockcode.Parse(f[0])
+		if err != nil {
+			return err
+		}
+		s := roster.Stock{Code: code}
+		if f[1] != "" {
+			if s.Updated, err = readr.ParseDate(f[1]); err != nil {
+				return err
+			}
+		}
+		if s.LastPower, err = strconv.ParseFloat(f[2], 64); err != nil {
+			return err
+		}
+		j.Done[code] = s
+	}
+	if last := lines[len(lines)-1]; last != "" {
+		// 去掉写了一半的行, 以免与之后追加的行连在一起
+		if err := os.Truncate(j.path, int64(len(b)-len(last))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore 把日志中已完成的股票s的最后更新日及权值记入s, s不在日志中时返回false
+func (j *Journal) Restore(s *roster.Stock) bool {
+	d, ok := j.Done[s.Code]
+	if ok {
+		s.Updated, s.LastPower = d.Updated, d.LastPower
+	}
+	return ok
+}
+
+// Record 记录股票s已完成, 可以由多个goroutine同时调用
+func (j *Journal) Record(s *roster.Stock) error {
+	line := fmt.Sprintf("%s,%s,%s\n", s.Code, formatDay(s.Updated), strconv.FormatFloat(s.LastPower, 'f', -1, 64))
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.f.WriteString(line); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+// Close 关闭日志, 保留文件
+func (j *Journal) Close() error {
+	return j.f.Close()
+}
+
+// Remove 关闭并删除日志
+func (j *Journal) Remove() error {
+	j.f.Close()
+	return os.Remove(j.path)
+}
+
+func formatDay(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(readr.DateLayout)
+}
@@ -0,0 +1,115 @@
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"stockstat/roster"
+	"stockstat/stockcode"
+	"testing"
+)
+
+func TestJournalResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), JournalName)
+	j, err := OpenJournal(path, ProviderIfeng, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := []roster.Stock{
+		{Code: stockcode.MustParse("600000"), Updated: day("2024-01-08"), LastPower: 9.747},
+		{Code: stockcode.MustParse("000001")}, // 没有数据
+	}
+	for i := range done {
+		if err := j.Record(&done[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j.Close()
+
+	// 中断时写了一半的行
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("600036.SH,2024-0")
+	f.Close()
+
+	j, err = OpenJournal(path, ProviderIfeng, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.Done) != 2 {
+		t.Fatalf("resumed %d stocks, want 2", len(j.Done))
+	}
+	s := roster.Stock{Code: done[0].Code}
+	if !j.Restore(&s) || !s.Updated.Equal(done[0].Updated) || s.LastPower != done[0].LastPower {
+		t.Errorf("restored %+v, want %+v", s, done[0])
+	}
+	if s := (roster.Stock{Code: stockcode.MustParse("600036")}); j.Restore(&s) {
+		t.Errorf("half-written line restored")
+	}
+	third := roster.Stock{Code: stockcode.MustParse("600036"), Updated: day("2024-01-05"), LastPower: 1}
+	if err := j.Record(&third); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	// 追加的行不与写了一半的行连在一起
+	j, err = OpenJournal(path, ProviderIfeng, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.Done) != 3 {
+		t.Errorf("resumed %d stocks after truncation, want 3", len(j.Done))
+	}
+	if err := j.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("journal not removed: %v", err)
+	}
+}
+
+func TestJournalFresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), JournalName)
+	j, err := OpenJournal(path, ProviderIfeng, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := roster.Stock{Code: stockcode.MustParse("600000"), Updated: day("2024-01-08"), LastPower: 1}
+	j.Record(&s)
+	j.Close()
+
+	tests := []struct {
+		name     string
+		provider string
+		restart  bool
+		data     string // 为空时不改写文件
+	}{
+		{"other provider", ProviderSina, false, ""},
+		{"restart", ProviderIfeng, true, ""},
+		{"bad line", ProviderIfeng, false, "#v1,ifeng\nnot a line\n"},
+		{"bad version", ProviderIfeng, false, "#v9,ifeng\n600000.SH,2024-01-08,1\n"},
+	}
+	for _, tt := range tests {
+		if tt.data != "" {
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			j, _ := OpenJournal(path, ProviderIfeng, true)
+			j.Record(&s)
+			j.Close()
+		}
+		j, err := OpenJournal(path, tt.provider, tt.restart)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(j.Done) != 0 {
+			t.Errorf("%s: resumed %d stocks, want a fresh journal", tt.name, len(j.Done))
+		}
+		j.Close()
+		if b, _ := os.ReadFile(path); string(b) != "#v1,"+tt.provider+"\n" {
+			t.Errorf("%s: journal %q, want only the header", tt.name, b)
+		}
+	}
+}
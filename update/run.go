@@ -0,0 +1,122 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/readr"
+	"stockstat/roster"
+	"sync"
+	"time"
+)
+
+// Options 是Run的下载设置, 见Fetcher
+type Options struct {
+	Rate    float64 // 每秒请求数, 0表示不限
+	Burst   int
+	PerHost int // 对同一主机的最大并发请求数, 0表示不限
+	Retries int
+	Restart bool         // 忽略上次中断的更新留下的日志, 重新更新全部股票
+	Policy  readr.Policy // 与数据文件冲突时的取舍
+}
+
+// ConflictFile 是输出目录中各股票与数据文件冲突的值的报告
+const ConflictFile = "conflicts.csv"
+
+// DefaultOptions 是命令行的缺省下载设置
+var DefaultOptions = Options{Rate: 2, Burst: 1, PerHost: 2, Retries: 3}
+
+// Run 从c.Provider更新股票列表中各股票的数据文件, 把每只股票的结果写到out, 再把最后更新日及权值写回股票列表。
+// 有股票失败时其余股票照常更新, 最后返回错误。与数据文件冲突的值写到输出目录中的ConflictFile。
+//
+// 已完成的股票记入数据目录中的更新日志(见Journal), 被中断(Ctrl-C)或有股票失败时再次运行只更新其余的股票,
+// 全部成功后删除日志。opts为nil时使用DefaultOptions。
+func Run(c *config.Config, out *cli.Output, opts *Options) error {
+	if opts == nil {
+		opts = &DefaultOptions
+	}
+	stocks, err := roster.Load(c.RosterPath())
+	if err != nil {
+		return err
+	}
+	f := &Fetcher{
+		Rate: opts.Rate, Burst: opts.Burst, PerHost: opts.PerHost, Retries: opts.Retries,
+		MinDelay: time.Second, MaxDelay: time.Minute,
+	}
+	src, err := NewSource(c.Provider, c.Source, f)
+	if err != nil {
+		return err
+	}
+	u := &Updater{Source: src, Policy: opts.Policy, Path: c.DataPath}
+	journal, err := OpenJournal(filepath.Join(c.DataDir, JournalName), c.Provider, opts.Restart)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	results := make([]*Result, len(stocks))
+	limit := c.Limiter()
+	var wg sync.WaitGroup
+	for i := range stocks {
+		s := &stocks[i]
+		if journal.Restore(s) {
+			results[i] = &Result{Stock: s, Status: StatusDone}
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			r := u.Stock(ctx, s)
+			if r.Err == nil {
+				if err := journal.Record(s); err != nil {
+					r.Status, r.Err = StatusFailed, err
+				}
+			}
+			results[i] = r
+		}(i)
+	}
+	wg.Wait()
+	interrupted := ctx.Err() != nil
+	stop()
+
+	failed := 0
+	records := make([][]string, len(results))
+	var conflicts [][]string
+	for i, r := range results {
+		if r.Status == StatusFailed {
+			failed++
+		}
+		records[i] = r.Record()
+		conflicts = append(conflicts, r.ConflictRecords()...)
+	}
+	if err := out.WriteTable(Header, records); err != nil {
+		journal.Close()
+		return err
+	}
+	report := &cli.Output{Format: cli.FormatCSV, Path: c.OutPath(ConflictFile)}
+	if err := report.WriteTable(ConflictHeader, conflicts); err != nil {
+		journal.Close()
+		return err
+	}
+	if err := roster.Save(c.RosterPath(), stocks); err != nil {
+		journal.Close()
+		return err
+	}
+	switch {
+	case interrupted:
+		journal.Close()
+		return errors.New("interrupted, run update again to resume")
+	case failed > 0:
+		journal.Close()
+		return fmt.Errorf("%d of %d stocks failed, run update again to retry them", failed, len(stocks))
+	}
+	return journal.Remove()
+}
@@ -0,0 +1,170 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"strings"
+	"time"
+)
+
+// DefaultSinaURL 是新浪复权历史交易页面的缺省地址, %s为6位代码
+const DefaultSinaURL = "http://vip.stock.finance.sina.com.cn/corp/go.php/vMS_FuQuanMarketHistory/stockid/%s.phtml"
+
+// SinaSource 从新浪的复权历史交易页面下载日K线, 每次请求一个季度:
+// BaseURL?year=2024&jidu=3, BaseURL中的%s替换为6位代码。
+//
+// 页面中id为FundHoldSharesTable的表格每行为
+// 日期, 开盘, 最高, 收盘, 最低, 成交量(股), 成交额(元), 复权因子,
+// 价格是后复权的, 除以复权因子并按分取整即为不复权的价格, 复权因子即为权值。
+type SinaSource struct {
+	BaseURL string
+	Fetcher *Fetcher // 为nil时使用DefaultFetcher
+	// Now 返回当前时间, 决定请求到哪个季度, 为nil时使用time.Now
+	Now func() time.Time
+}
+
+// sinaFirstYear 是since为零值时请求的第一年
+const sinaFirstYear = 1990
+
+// URL 返回下载股票code某年第quarter季度(1-4)数据的地址
+func (src *SinaSource) URL(code stockcode.Code, year, quarter int) string {
+	u := src.BaseURL
+	if strings.Contains(u, "%s") {
+		u = fmt.Sprintf(u, code.Number)
+	}
+	sep := "?"
+	if strings.Contains(u, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%syear=%d&jidu=%d", u, sep, year, quarter)
+}
+
+// Fetch 实现Source, 逐个季度请求自since所在季度至今的数据
+func (src *SinaSource) Fetch(ctx context.Context, code stockcode.Code, since time.Time) (*readr.Frame, error) {
+	now := time.Now
+	if src.Now != nil {
+		now = src.Now
+	}
+	y, q := sinaFirstYear, 1
+	if !since.IsZero() {
+		y, q = since.Year(), quarterOf(since)
+	}
+	endY, endQ := now().Year(), quarterOf(now())
+
+	var bars []readr.Bar
+	for ; y < endY || y == endY && q <= endQ; y, q = nextQuarter(y, q) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		err := fetcherOf(src.Fetcher).Get(ctx, src.URL(code, y, q), func(r io.Reader) error {
+			b, err := parseSina(r)
+			bars = append(bars, b...)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return frameOf(sinaColumns, bars, since), nil
+}
+
+// Parse 实现Source, 解析一个季度的页面
+func (src *SinaSource) Parse(r io.Reader) (*readr.Frame, error) {
+	bars, err := parseSina(r)
+	if err != nil {
+		return nil, err
+	}
+	return frameOf(sinaColumns, bars, time.Time{}), nil
+}
+
+func quarterOf(t time.Time) int {
+	return (int(t.Month())-1)/3 + 1
+}
+
+func nextQuarter(y, q int) (int, int) {
+	if q == 4 {
+		return y + 1, 1
+	}
+	return y, q + 1
+}
+
+// sinaColumns 是新浪数据换算后有的列
+var sinaColumns = []readr.Column{
+	readr.ColOpen, readr.ColHigh, readr.ColClose, readr.ColLow, readr.ColVolumn, readr.ColAmount, readr.ColPower,
+}
+
+var (
+	sinaTable = []byte(`id="FundHoldSharesTable"`)
+	sinaRow   = regexp.MustCompile(`(?is)<tr[^>]*>(.*?)</tr>`)
+	sinaCell  = regexp.MustCompile(`(?is)<td[^>]*>(.*?)</td>`)
+	sinaTag   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// errNoSinaTable 表示页面中没有数据表格, 通常是代码错误或页面改版
+var errNoSinaTable = errors.New("no FundHoldSharesTable in page")
+
+// parseSina 解析新浪复权历史交易页面, 返回换算后的各行。表头等不是数据的行被跳过。
+func parseSina(r io.Reader) ([]readr.Bar, error) {
+	page, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	i := bytes.Index(page, sinaTable)
+	if i < 0 {
+		return nil, errNoSinaTable
+	}
+	page = page[i:]
+	if j := bytes.Index(page, []byte("</table>")); j >= 0 {
+		page = page[:j]
+	}
+
+	var bars []readr.Bar
+	for _, row := range sinaRow.FindAllSubmatch(page, -1) {
+		var cells []string
+		for _, c := range sinaCell.FindAllSubmatch(row[1], -1) {
+			cells = append(cells, strings.TrimSpace(string(sinaTag.ReplaceAll(c[1], nil))))
+		}
+		if len(cells) < 8 {
+			continue
+		}
+		t, err := readr.ParseDate(cells[0])
+		if err != nil {
+			continue // 表头
+		}
+		bar, err := sinaBar(t, cells[1:8])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", cells[0], err)
+		}
+		bars = append(bars, bar)
+	}
+	return bars, nil
+}
+
+// sinaBar 由开盘, 最高, 收盘, 最低, 成交量, 成交额, 复权因子换算出不复权的一行
+func sinaBar(t time.Time, cells []string) (readr.Bar, error) {
+	var v [7]float64
+	for i, s := range cells {
+		x, err := readr.ParseNumber(s)
+		if err != nil {
+			return readr.Bar{}, err
+		}
+		v[i] = x
+	}
+	pow := v[6]
+	if pow <= 0 {
+		return readr.Bar{}, fmt.Errorf("bad factor %q", cells[6])
+	}
+	raw := func(p float64) float64 { return math.Round(p/pow*100) / 100 }
+	return readr.Bar{
+		Date: t.Format(readr.DateLayout), Time: t,
+		Open: raw(v[0]), High: raw(v[1]), Close: raw(v[2]), Low: raw(v[3]),
+		Volumn: v[4], Amount: v[5], Power: pow,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"time"
+)
+
+// Source 是日K线的行情源。
+// 各行情源的数据都换算为数据目录的格式: 日期为2006-01-02, 价格为不复权的实际成交价(元),
+// 成交量以股为单位, 成交额以元为单位, 权值为后复权因子。行情源没有的列为nil。
+type Source interface {
+	// Fetch 下载股票code自since(含)起的日K线, 按日期升序。since为零值时下载全部历史。
+	Fetch(ctx context.Context, code stockcode.Code, since time.Time) (*readr.Frame, error)
+	// Parse 解析一次下载得到的内容
+	Parse(r io.Reader) (*readr.Frame, error)
+}
+
+// 行情源的名字, 见NewSource
+const (
+	ProviderIfeng = "ifeng"
+	ProviderSina  = "sina"
+)
+
+// NewSource 返回名为name的行情源, baseURL为空时使用其缺省地址, 各请求经由f下载
+func NewSource(name, baseURL string, f *Fetcher) (Source, error) {
+	switch name {
+	case ProviderIfeng:
+		if baseURL == "" {
+			baseURL = DefaultIfengURL
+		}
+		return &IfengSource{BaseURL: baseURL, Fetcher: f}, nil
+	case ProviderSina:
+		if baseURL == "" {
+			baseURL = DefaultSinaURL
+		}
+		return &SinaSource{BaseURL: baseURL, Fetcher: f}, nil
+	}
+	return nil, fmt.Errorf("unknown provider %q, want %s or %s", name, ProviderIfeng, ProviderSina)
+}
+
+// fetcherOf 返回f, f为nil时返回DefaultFetcher
+func fetcherOf(f *Fetcher) *Fetcher {
+	if f == nil {
+		return DefaultFetcher
+	}
+	return f
+}
+
+// frameOf 把bars按日期排序, 去掉from之前的行及重复的日期(只取第一行), 返回只有cols各列的Frame
+func frameOf(cols []readr.Column, bars []readr.Bar, from time.Time) *readr.Frame {
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].Time.Before(bars[j].Time) })
+	frame := readr.NewFrame(cols, len(bars))
+	for i := range bars {
+		if bars[i].Time.Before(from) {
+			continue
+		}
+		if n := frame.Len(); n > 0 && !bars[i].Time.After(frame.Times[n-1]) {
+			continue
+		}
+		frame.Append(&bars[i])
+	}
+	return frame
+}
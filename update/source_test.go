@@ -0,0 +1,150 @@
+package update
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"stockstat/readr"
+	"stockstat/stockcode"
+	"testing"
+	"time"
+)
+
+// testdata中录下的浦发银行2024年1月2日至8日的应答:
+// sina_quarter.html是新浪2024年第1季度的复权历史交易页面(新的在前, 后复权价格, 复权因子9.747),
+// ifeng.json是凤凰网的不复权日K线(成交量以手为单位)。
+var sample = []struct {
+	date                   string
+	open, high, close, low float64
+	volume                 float64 // 股
+}{
+	{"2024-01-02", 6.59, 6.63, 6.58, 6.53, 19861400},
+	{"2024-01-03", 6.58, 6.61, 6.60, 6.55, 18520300},
+	{"2024-01-04", 6.60, 6.64, 6.62, 6.57, 21890500},
+	{"2024-01-05", 6.62, 6.78, 6.75, 6.61, 45327800},
+	{"2024-01-08", 6.75, 6.80, 6.71, 6.69, 30218700},
+}
+
+const samplePower = 9.747
+
+// serveFile 返回只应答query为want的请求的服务器, 应答为testdata中的文件name
+func serveFile(t *testing.T, name string, want map[string]string) *httptest.Server {
+	body, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range want {
+			if r.URL.Query().Get(k) != v {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// checkSample 检查frm为sample[from:]换算成的数据目录格式
+func checkSample(t *testing.T, name string, frm *readr.Frame, from int, power bool) {
+	t.Helper()
+	want := sample[from:]
+	if frm.Len() != len(want) {
+		t.Fatalf("%s: %d rows, want %d", name, frm.Len(), len(want))
+	}
+	if frm.Has(readr.ColPower) != power {
+		t.Errorf("%s: has pow %v, want %v", name, frm.Has(readr.ColPower), power)
+	}
+	cent := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	for i, w := range want {
+		b := frm.Bar(i)
+		if b.Date != w.date || !b.Time.Equal(day(w.date)) {
+			t.Errorf("%s: row %d date %q %v, want %s", name, i, b.Date, b.Time, w.date)
+		}
+		if !cent(b.Open, w.open) || !cent(b.High, w.high) || !cent(b.Close, w.close) || !cent(b.Low, w.low) {
+			t.Errorf("%s: %s prices %v %v %v %v, want %v %v %v %v", name, w.date,
+				b.Open, b.High, b.Close, b.Low, w.open, w.high, w.close, w.low)
+		}
+		if b.Volumn != w.volume {
+			t.Errorf("%s: %s volume %v, want %v shares", name, w.date, b.Volumn, w.volume)
+		}
+		if power && b.Power != samplePower {
+			t.Errorf("%s: %s pow %v, want %v", name, w.date, b.Power, samplePower)
+		}
+	}
+}
+
+func TestSinaSource(t *testing.T) {
+	f, err := os.Open("testdata/sina_quarter.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	src := &SinaSource{Now: func() time.Time { return day("2024-01-09") }}
+	frm, err := src.Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSample(t, "sina parse", frm, 0, true)
+	if !frm.Has(readr.ColAmount) || frm.Amounts[0] != 131020231 {
+		t.Errorf("sina parse: amounts %v, want 131020231 first", frm.Amounts)
+	}
+
+	ts := serveFile(t, "sina_quarter.html", map[string]string{"year": "2024", "jidu": "1"})
+	src.BaseURL = ts.URL + "/stockid/%s.phtml"
+	frm, err = src.Fetch(context.Background(), stockcode.MustParse("600000"), day("2024-01-03"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSample(t, "sina fetch", frm, 1, true)
+}
+
+func TestIfengSource(t *testing.T) {
+	f, err := os.Open("testdata/ifeng.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	src := &IfengSource{}
+	frm, err := src.Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSample(t, "ifeng parse", frm, 0, false)
+
+	ts := serveFile(t, "ifeng.json", map[string]string{"code": "sh600000", "type": "last", "begin": "2024-01-03"})
+	src.BaseURL = ts.URL + "/akdaily/"
+	// 服务器忽略begin, 多出的一天被去掉
+	frm, err = src.Fetch(context.Background(), stockcode.MustParse("600000"), day("2024-01-03"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSample(t, "ifeng fetch", frm, 1, false)
+}
+
+func TestSourcesAgree(t *testing.T) {
+	parse := func(src Source, name string) *readr.Frame {
+		f, err := os.Open("testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		frm, err := src.Parse(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return frm
+	}
+	sina, ifeng := parse(&SinaSource{}, "sina_quarter.html"), parse(&IfengSource{}, "ifeng.json")
+	// 两者合并时除权值外没有冲突
+	_, report, err := readr.Merge(sina, ifeng, readr.PolicyReject)
+	if err != nil {
+		t.Fatalf("merging sina and ifeng: %v", err)
+	}
+	if len(report.Added) != 0 || report.Changed != 0 {
+		t.Errorf("merge report %+v, want no added or changed rows", report)
+	}
+}
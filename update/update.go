@@ -0,0 +1,212 @@
+// package update 从行情源增量下载股票列表中各股票的日K线, 合并到数据目录,
+// 并把最后更新日及权值写回股票列表。
+//
+// 每只股票请求自股票列表中最后更新日(没有记录时为数据文件最后一天)起的数据, 由readr.Merge合并到数据文件:
+// 重叠的日期用于发现行情源修订过的值及复权基准的变化, 冲突按Updater.Policy取舍并列入结果。
+// 行情源见Source; 行情源没有复权因子时, 新增各行沿用数据文件最后的权值, 其间的除权因此会丢失:
+// 由价格推断出的可能的除权日(见events.Infer)列入Result.Suspects, 应从有复权因子的行情源重新下载核对。
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"stockstat/events"
+	"stockstat/readr"
+	"stockstat/roster"
+	"stockstat/safefile"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 更新结果
+const (
+	StatusUpdated = "updated" // 有新数据, 已写入
+	StatusCurrent = "current" // 没有新数据
+	StatusFailed  = "failed"
+	StatusDone    = "done" // 在被中断的上次更新中已完成, 这次没有请求
+)
+
+// Result 是一只股票的更新结果
+type Result struct {
+	Stock       *roster.Stock
+	Status      string
+	Added       int    // 新增的行数
+	Changed     int    // 值有改变的原有行数
+	First, Last string // 新增的第一天及最后一天
+	Conflicts   []readr.Conflict
+	// Suspects 是行情源没有复权因子时, 新增各行中由价格推断出的可能的除权日, 其权值没有改变
+	Suspects []events.Proposal
+	Err      error
+}
+
+// Header 是Result.Record的表头
+var Header = []string{"code", "name", "status", "added", "changed", "conflicts", "first", "last", "suspects", "error"}
+
+// Record 返回r的一行报告
+func (r *Result) Record() []string {
+	msg := ""
+	if r.Err != nil {
+		msg = r.Err.Error()
+	}
+	suspects := make([]string, len(r.Suspects))
+	for i, p := range r.Suspects {
+		suspects[i] = p.Date
+	}
+	return []string{
+		r.Stock.Code.String(), r.Stock.Name, r.Status,
+		strconv.Itoa(r.Added), strconv.Itoa(r.Changed), strconv.Itoa(len(r.Conflicts)), r.First, r.Last,
+		strings.Join(suspects, ";"), msg,
+	}
+}
+
+// ConflictHeader 是ConflictRecords的表头
+var ConflictHeader = []string{"code", "date", "column", "old", "new"}
+
+// ConflictRecords 返回r中各冲突的报告
+func (r *Result) ConflictRecords() [][]string {
+	records := make([][]string, len(r.Conflicts))
+	for i, c := range r.Conflicts {
+		records[i] = []string{
+			r.Stock.Code.String(), c.Date, c.Column.String(),
+			strconv.FormatFloat(c.Old, 'f', -1, 64), strconv.FormatFloat(c.New, 'f', -1, 64),
+		}
+	}
+	return records
+}
+
+// Updater 把从Source下载的数据合并到数据文件
+type Updater struct {
+	Source Source
+	Policy readr.Policy // 与数据文件冲突时的取舍
+	// Path 返回股票数据文件的路径, 见config.Config.DataPath
+	Path func(code string) string
+}
+
+// Stock 更新股票s的数据文件, 成功时把最后更新日及权值记入s
+func (u *Updater) Stock(ctx context.Context, s *roster.Stock) *Result {
+	r := &Result{Stock: s, Status: StatusFailed}
+	path := u.Path(s.Code.String())
+	old, err := loadData(ctx, path)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+
+	// 没有数据文件时从上市日(未知时为全部历史)下载; 没有记录或数据文件落后于记录时从数据文件最后一天下载
+	since := s.Updated
+	var last time.Time
+	if old != nil && old.Len() > 0 {
+		last = old.Times[old.Len()-1]
+	}
+	if last.IsZero() || since.IsZero() || since.After(last) {
+		since = last
+	}
+	if since.IsZero() {
+		since = s.Listed
+	}
+	add, err := u.Source.Fetch(ctx, s.Code, since)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+
+	power := s.LastPower
+	if power == 0 {
+		power = 1
+	}
+	if old != nil {
+		setPower(old, power)
+	}
+	frm, report, err := readr.Merge(old, add, u.Policy)
+	if report != nil {
+		r.Conflicts = report.Conflicts
+	}
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	setPower(frm, power)
+	r.Status = StatusCurrent
+	if len(report.Added) > 0 || report.Changed > 0 {
+		err := safefile.WriteFile(path, func(w io.Writer) error {
+			return frm.WriteCSV(w, &readr.StockDataFormat)
+		})
+		if err != nil {
+			r.Status, r.Err = StatusFailed, err
+			return r
+		}
+		r.Status, r.Added, r.Changed = StatusUpdated, len(report.Added), report.Changed
+		if n := len(report.Added); n > 0 {
+			r.First, r.Last = report.Added[0], report.Added[n-1]
+		}
+		if !add.Has(readr.ColPower) {
+			r.Suspects = suspects(frm, report.Added, s)
+		}
+	}
+	if n := frm.Len(); n > 0 {
+		s.Updated, s.LastPower = frm.Times[n-1], frm.Power[n-1]
+	}
+	return r
+}
+
+// loadData 读取数据文件path中不复权的数据, 文件不存在时返回nil。
+// 旧版sina2ifeng换算过的文件没有标记, 也是不复权的数据(见readr.IsLegacy), 写回时加上标记。
+func loadData(ctx context.Context, path string) (*readr.Frame, error) {
+	m, err := readr.ReadMarker(path)
+	if errors.Is(err, readr.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		legacy, err := readr.IsLegacy(path)
+		if err != nil {
+			return nil, err
+		}
+		if !legacy {
+			// 没有标记的可能是新浪的后复权数据, 不能与不复权的数据混在一起
+			return nil, fmt.Errorf("%s: not converted to raw prices, run stockstat sina first", path)
+		}
+	}
+	frm, err := readr.Load(ctx, path, &readr.Options{Header: true, NoCache: true})
+	if errors.Is(err, readr.ErrNoData) {
+		return nil, nil
+	}
+	return frm, err
+}
+
+// suspects 返回合并后的frm中日期为added之一的各行里, 由价格推断出的可能的除权日
+func suspects(frm *readr.Frame, added []string, s *roster.Stock) []events.Proposal {
+	isAdded := make(map[string]bool, len(added))
+	for _, d := range added {
+		isAdded[d] = true
+	}
+	board := s.Board
+	if board == "" {
+		board = s.Code.Board()
+	}
+	var res []events.Proposal
+	for _, p := range events.Infer(frm, board, events.IsST(s.Name)) {
+		if isAdded[p.Date] {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// setPower 把frm中没有或为NaN的权值设为power
+func setPower(frm *readr.Frame, power float64) {
+	if !frm.Has(readr.ColPower) {
+		frm.Power = make([]float64, frm.Len())
+	}
+	for i, p := range frm.Power {
+		if p == 0 || math.IsNaN(p) {
+			frm.Power[i] = power
+		}
+	}
+}
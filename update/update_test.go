@@ -0,0 +1,193 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/readr"
+	"stockstat/roster"
+	"stockstat/stockcode"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ifengRecord 返回凤凰网JSON中的一行, lots为成交量(手)
+func ifengRecord(date string, open, high, close, low, lots float64) string {
+	return fmt.Sprintf(`["%s","%.3f","%.3f","%.3f","%.3f","%.2f","0.00","0.00","0","0","0","0","0","0","0.10"]`,
+		date, open, high, close, low, lots)
+}
+
+// ifengServer 返回按begin以后(服务器忽略begin)的rows应答的凤凰网行情源, 各请求的begin记入begins
+func ifengServer(t *testing.T, rows []string) (*IfengSource, *[]string) {
+	var (
+		mu     sync.Mutex
+		begins []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		begins = append(begins, r.URL.Query().Get("begin"))
+		mu.Unlock()
+		fmt.Fprintf(w, `{"record":[%s]}`, strings.Join(rows, ","))
+	}))
+	t.Cleanup(ts.Close)
+	return &IfengSource{BaseURL: ts.URL + "/akdaily/"}, &begins
+}
+
+const (
+	markedData = "#stockstat v1 adjust=none\r\n#date,open,high,close,low,volume,pow\r\n" +
+		"2024-01-02,10.000,10.500,10.200,9.900,100000,1.500\r\n" +
+		"2024-01-03,10.200,10.600,10.400,10.100,120000,1.500\r\n" +
+		"2024-01-04,10.400,10.800,10.700,10.300,150000,1.500\r\n"
+	// 旧版sina2ifeng写出的, 没有标记
+	legacyData = "#date,open,high,cloe,low,volumn,pow\r\n" +
+		"2024-01-02,10.000,10.500,10.200,9.900,100000,1.500\r\n" +
+		"2024-01-03,10.200,10.600,10.400,10.100,120000,1.500\r\n" +
+		"2024-01-04,10.400,10.800,10.700,10.300,150000,1.500\r\n"
+	// 新浪下载的后复权数据
+	sinaData = "date,open,high,close,low,volume,amount,pow\r\n" +
+		"2024-01-02,15.000,15.750,15.300,14.850,100000,1020000,1.500\r\n"
+)
+
+func TestUpdaterStock(t *testing.T) {
+	fetched := []string{
+		ifengRecord("2024-01-03", 10.2, 10.6, 10.4, 10.1, 1200), // 服务器忽略begin, 多出的行被去掉
+		ifengRecord("2024-01-04", 10.4, 10.8, 10.7, 10.3, 1500), // 与数据文件重叠的一天
+		ifengRecord("2024-01-05", 10.7, 11.0, 10.9, 10.6, 1800),
+		ifengRecord("2024-01-08", 10.9, 11.2, 11.1, 10.8, 2000),
+	}
+	tests := []struct {
+		name, data string
+		fetched    []string
+		status     string
+		added      int
+		rows       int    // 数据文件更新后的行数
+		err        string // 错误信息的一部分
+	}{
+		{"marked", markedData, fetched, StatusUpdated, 2, 5, ""},
+		{"legacy", legacyData, fetched, StatusUpdated, 2, 5, ""},
+		{"current", markedData, fetched[:2], StatusCurrent, 0, 3, ""},
+		{"sina", sinaData, fetched, StatusFailed, 0, 0, "not converted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "600000.csv")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			src, begins := ifengServer(t, tt.fetched)
+			u := &Updater{Source: src, Policy: readr.PolicyReject, Path: func(string) string { return path }}
+			s := &roster.Stock{Code: stockcode.MustParse("600000"), Updated: day("2024-01-04"), LastPower: 1.5}
+
+			r := u.Stock(context.Background(), s)
+			if tt.err != "" {
+				if r.Err == nil || !strings.Contains(r.Err.Error(), tt.err) || r.Status != tt.status {
+					t.Fatalf("status %s, err %v, want %s, %q", r.Status, r.Err, tt.status, tt.err)
+				}
+				if len(*begins) != 0 {
+					t.Errorf("fetched %v for a file that cannot be updated", *begins)
+				}
+				return
+			}
+			if r.Err != nil || r.Status != tt.status || r.Added != tt.added || r.Changed != 0 || len(r.Conflicts) != 0 {
+				t.Fatalf("result %+v, want %s with %d added and no conflicts", r, tt.status, tt.added)
+			}
+			// 只请求自最后更新日起的数据, 重叠的一天用于比较
+			if len(*begins) != 1 || (*begins)[0] != "2024-01-04" {
+				t.Errorf("requested begin %v, want [2024-01-04]", *begins)
+			}
+			frm, err := readr.Load(context.Background(), path, &readr.Options{Header: true, NoCache: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if frm.Len() != tt.rows {
+				t.Fatalf("data file has %d rows, want %d", frm.Len(), tt.rows)
+			}
+			last := frm.Bar(frm.Len() - 1)
+			if tt.added > 0 && (last.Date != "2024-01-08" || last.Close != 11.1 || last.Volumn != 200000 || last.Power != 1.5) {
+				t.Errorf("last row %+v, want 2024-01-08 close 11.1, volume 200000, pow 1.5", last)
+			}
+			if m, err := readr.ReadMarker(path); tt.added > 0 && (err != nil || m == nil) {
+				t.Errorf("updated file has no marker: %v", err)
+			}
+			if s.Updated.Format(readr.DateLayout) != last.Date || s.LastPower != 1.5 {
+				t.Errorf("stock updated %v, power %v, want %s, 1.5", s.Updated, s.LastPower, last.Date)
+			}
+		})
+	}
+}
+
+func TestRunWritesRoster(t *testing.T) {
+	src, begins := ifengServer(t, []string{
+		ifengRecord("2024-01-04", 10.4, 10.8, 10.7, 10.3, 1500),
+		ifengRecord("2024-01-05", 10.7, 11.0, 10.9, 10.6, 1800),
+	})
+	c := config.Default()
+	c.DataDir, c.OutDir = t.TempDir(), t.TempDir()
+	c.Provider, c.Source = ProviderIfeng, src.BaseURL
+	if err := os.WriteFile(c.DataPath("600000"), []byte(legacyData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stocks := roster.List{{Code: stockcode.MustParse("600000"), Name: "浦发银行"}}
+	if err := roster.Save(c.RosterPath(), stocks); err != nil {
+		t.Fatal(err)
+	}
+	out := &cli.Output{Format: cli.FormatCSV, Path: filepath.Join(c.OutDir, "update.csv")}
+	if err := Run(c, out, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	// 股票列表中没有最后更新日, 从数据文件最后一天起请求
+	if len(*begins) != 1 || (*begins)[0] != "2024-01-04" {
+		t.Errorf("requested begin %v, want [2024-01-04]", *begins)
+	}
+	got, err := roster.Load(c.RosterPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := got[0]; s.Updated.Format(readr.DateLayout) != "2024-01-05" || s.LastPower != 1.5 {
+		t.Errorf("roster updated %v, power %v, want 2024-01-05, 1.5", s.Updated, s.LastPower)
+	}
+	if _, err := os.Stat(filepath.Join(c.DataDir, JournalName)); !os.IsNotExist(err) {
+		t.Errorf("journal left after a successful update: %v", err)
+	}
+}
+
+func day(s string) time.Time {
+	t, err := readr.ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestUpdaterSuspects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "600000.csv")
+	if err := os.WriteFile(path, []byte(markedData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 1月5日10送转10, 凤凰网没有权值, 价格跌破跌停价
+	src, _ := ifengServer(t, []string{
+		ifengRecord("2024-01-04", 10.4, 10.8, 10.7, 10.3, 1500),
+		ifengRecord("2024-01-05", 5.35, 5.5, 5.4, 5.3, 3000),
+		ifengRecord("2024-01-08", 5.4, 5.6, 5.5, 5.35, 2000),
+	})
+	u := &Updater{Source: src, Path: func(string) string { return path }}
+	s := &roster.Stock{Code: stockcode.MustParse("600000"), Updated: day("2024-01-04"), LastPower: 1.5}
+	r := u.Stock(context.Background(), s)
+	if r.Err != nil || r.Added != 2 {
+		t.Fatalf("result %+v", r)
+	}
+	if len(r.Suspects) != 1 || r.Suspects[0].Date != "2024-01-05" || r.Suspects[0].Ratio != 2 {
+		t.Errorf("suspects %+v, want 2024-01-05 with ratio 2", r.Suspects)
+	}
+	if rec := r.Record(); rec[8] != "2024-01-05" {
+		t.Errorf("record %q, want suspects 2024-01-05", rec)
+	}
+}
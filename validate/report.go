@@ -0,0 +1,170 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"stockstat/readr"
+	"strconv"
+	"sync"
+)
+
+// Report 汇总多只股票的检查结果
+type Report struct {
+	mu     sync.Mutex
+	Issues []Issue
+	Stocks map[string]*StockSummary
+}
+
+// StockSummary 是一只股票的检查结果
+type StockSummary struct {
+	Code   string
+	Rows   int
+	Counts [Error + 1]int // 各严重程度的问题数
+	Err    error          // 无法读取数据时的错误, 同时记为CheckRead问题
+}
+
+// Worst 返回该股票最严重的问题级别, 没有问题时返回-1
+func (s *StockSummary) Worst() Severity {
+	for sev := Error; sev >= Info; sev-- {
+		if s.Counts[sev] > 0 {
+			return sev
+		}
+	}
+	return -1
+}
+
+// NewReport 返回空的报告
+func NewReport() *Report {
+	return &Report{Stocks: make(map[string]*StockSummary)}
+}
+
+// Add 记录股票code的检查结果, 可并发调用
+func (r *Report) Add(code string, rows int, issues []Issue, err error) {
+	s := &StockSummary{Code: code, Rows: rows, Err: err}
+	for _, is := range issues {
+		s.Counts[is.Severity]++
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Issues = append(r.Issues, issues...)
+	r.Stocks[code] = s
+}
+
+// CheckFile 读取并检查股票code的数据文件path, 把结果记入r
+func (r *Report) CheckFile(ctx context.Context, code, path string, opts *Options) {
+	var parseIssues []Issue
+	ropts := &readr.Options{Header: true, Lenient: true, Warn: func(e *readr.ParseError) {
+		parseIssues = append(parseIssues, ParseIssue(code, e))
+	}}
+	frame, err := readr.Load(ctx, path, ropts)
+	if err != nil {
+		issue := Issue{Code: code, Check: CheckRead, Severity: Error, Message: err.Error()}
+		r.Add(code, 0, append(parseIssues, issue), err)
+		return
+	}
+	r.Add(code, frame.Len(), append(parseIssues, Check(code, frame, opts)...), nil)
+}
+
+// sorted 返回按代码排序的各股票结果
+func (r *Report) sorted() []*StockSummary {
+	list := make([]*StockSummary, 0, len(r.Stocks))
+	for _, s := range r.Stocks {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// 各种报告的表头
+var (
+	IssueHeader  = []string{"code", "row", "date", "check", "severity", "message"}
+	StockHeader  = []string{"code", "rows", "errors", "warnings", "infos", "worst", "error"}
+	MarketHeader = []string{"check", "severity", "issues", "stocks"}
+)
+
+// IssueRecords 返回严重程度不低于min的各个问题, 按代码及行号排序
+func (r *Report) IssueRecords(min Severity) [][]string {
+	issues := append([]Issue(nil), r.Issues...)
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Code != issues[j].Code {
+			return issues[i].Code < issues[j].Code
+		}
+		return issues[i].Row < issues[j].Row
+	})
+	var records [][]string
+	for _, is := range issues {
+		if is.Severity < min {
+			continue
+		}
+		records = append(records, []string{is.Code, strconv.Itoa(is.Row), is.Date, is.Check, is.Severity.String(), is.Message})
+	}
+	return records
+}
+
+// StockRecords 返回每只股票的问题数, 只列出最严重的问题不低于min的股票
+func (r *Report) StockRecords(min Severity) [][]string {
+	var records [][]string
+	for _, s := range r.sorted() {
+		worst := s.Worst()
+		if worst < min {
+			continue
+		}
+		errText, worstText := "", "ok"
+		if s.Err != nil {
+			errText = s.Err.Error()
+		}
+		if worst >= Info {
+			worstText = worst.String()
+		}
+		records = append(records, []string{s.Code, strconv.Itoa(s.Rows),
+			strconv.Itoa(s.Counts[Error]), strconv.Itoa(s.Counts[Warning]), strconv.Itoa(s.Counts[Info]),
+			worstText, errText})
+	}
+	return records
+}
+
+// MarketRecords 返回全市场各检查项的问题数及涉及的股票数, 最后一行为全部股票的汇总
+func (r *Report) MarketRecords(min Severity) [][]string {
+	type key struct {
+		check string
+		sev   Severity
+	}
+	issues := make(map[key]int)
+	stocks := make(map[key]map[string]bool)
+	total := 0
+	for _, is := range r.Issues {
+		if is.Severity < min {
+			continue
+		}
+		total++
+		k := key{is.Check, is.Severity}
+		issues[k]++
+		if stocks[k] == nil {
+			stocks[k] = make(map[string]bool)
+		}
+		stocks[k][is.Code] = true
+	}
+	keys := make([]key, 0, len(issues))
+	for k := range issues {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].sev != keys[j].sev {
+			return keys[i].sev > keys[j].sev
+		}
+		return keys[i].check < keys[j].check
+	})
+	var records [][]string
+	for _, k := range keys {
+		records = append(records, []string{k.check, k.sev.String(), strconv.Itoa(issues[k]), strconv.Itoa(len(stocks[k]))})
+	}
+	bad := 0
+	for _, s := range r.Stocks {
+		if s.Worst() >= min {
+			bad++
+		}
+	}
+	records = append(records, []string{"total", "", strconv.Itoa(total), fmt.Sprintf("%d/%d", bad, len(r.Stocks))})
+	return records
+}
@@ -0,0 +1,133 @@
+package validate
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"stockstat/calendar"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/readr"
+	"stockstat/roster"
+	"stockstat/stockcode"
+	"strings"
+	"testing"
+)
+
+// testData 准备数据目录: 600000有一行开高收低不对及一行无法解析,
+// 000001没有数据文件, 600036没有问题
+func testData(t *testing.T) *config.Config {
+	t.Helper()
+	c := config.Default()
+	c.DataDir = t.TempDir()
+	write := func(code string, frame *readr.Frame, extra string) {
+		f, err := os.Create(c.DataPath(code))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := frame.WriteCSV(f, &readr.StockDataFormat); err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(extra)
+	}
+	write("600000", testFrame(ok("2024-01-02", 10), bar{"2024-01-03", 10, 9.5, 10, 10.5, 1000, 1}, ok("2024-01-04", 10)),
+		"2024-01-05,abc,10.5,10,9.5,1000,1\r\n")
+	write("600036", testFrame(ok("2024-01-02", 30), ok("2024-01-03", 30.5)), "")
+	var stocks roster.List
+	for _, code := range []string{"600000", "000001", "600036"} {
+		stocks = append(stocks, roster.Stock{Code: stockcode.MustParse(code)})
+	}
+	if err := roster.Save(c.RosterPath(), stocks); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func runReport(t *testing.T, c *config.Config, format, kind string, min Severity) string {
+	t.Helper()
+	out := &cli.Output{Format: format, Path: filepath.Join(t.TempDir(), "report")}
+	err := Run(c, out, &Options{Calendar: calendar.Default()}, kind, min)
+	if err == nil || err.Error() != "2 of 3 stocks have errors" {
+		t.Errorf("Run(%s) = %v, want 2 of 3 stocks have errors", kind, err)
+	}
+	b, err := os.ReadFile(out.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestReportCSV(t *testing.T) {
+	c := testData(t)
+	records, err := csv.NewReader(strings.NewReader(runReport(t, c, cli.FormatCSV, ReportIssues, Info))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(records[0], IssueHeader) {
+		t.Errorf("header %v", records[0])
+	}
+	var got [][]string
+	for _, r := range records[1:] {
+		got = append(got, []string{r[0], r[1], r[2], r[3], r[4]})
+	}
+	want := [][]string{
+		{"000001.SZ", "0", "", CheckRead, "error"},
+		{"600000.SH", "1", "2024-01-03", CheckOHLC, "error"},
+		{"600000.SH", "6", "", CheckParse, "error"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues %v, want %v", got, want)
+	}
+
+	market := runReport(t, c, cli.FormatCSV, ReportMarket, Info)
+	wantMarket := "check,severity,issues,stocks\n" +
+		"ohlc,error,1,1\n" +
+		"parse,error,1,1\n" +
+		"read,error,1,1\n" +
+		"total,,3,2/3\n"
+	if market != wantMarket {
+		t.Errorf("market report\n%s\nwant\n%s", market, wantMarket)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	c := testData(t)
+	var got []map[string]string
+	if err := json.Unmarshal([]byte(runReport(t, c, cli.FormatJSON, ReportStocks, Info)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("stocks %v, want 000001 and 600000", got)
+	}
+	if got[0]["code"] != "000001.SZ" || got[0]["worst"] != "error" || got[0]["rows"] != "0" || got[0]["error"] == "" {
+		t.Errorf("missing stock %v", got[0])
+	}
+	want := map[string]string{"code": "600000.SH", "rows": "3", "errors": "2", "warnings": "0", "infos": "0", "worst": "error", "error": ""}
+	if !reflect.DeepEqual(got[1], want) {
+		t.Errorf("stock %v, want %v", got[1], want)
+	}
+}
+
+func TestRecordsMin(t *testing.T) {
+	r := NewReport()
+	r.Add("600000", 10, []Issue{
+		{Code: "600000", Row: 3, Check: CheckGap, Severity: Info},
+		{Code: "600000", Row: 1, Check: CheckJump, Severity: Warning},
+	}, nil)
+	r.Add("600036", 10, nil, nil)
+	if got := r.IssueRecords(Warning); len(got) != 1 || got[0][3] != CheckJump {
+		t.Errorf("IssueRecords(warning) = %v", got)
+	}
+	if got := r.IssueRecords(Info); len(got) != 2 || got[0][1] != "1" {
+		t.Errorf("IssueRecords(info) = %v, want sorted by row", got)
+	}
+	if got := r.StockRecords(Error); len(got) != 0 {
+		t.Errorf("StockRecords(error) = %v", got)
+	}
+	if got := r.MarketRecords(Warning); len(got) != 2 || !reflect.DeepEqual(got[1], []string{"total", "", "1", "1/2"}) {
+		t.Errorf("MarketRecords(warning) = %v", got)
+	}
+}
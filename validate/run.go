@@ -0,0 +1,71 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"stockstat/catalog"
+	"stockstat/cli"
+	"stockstat/config"
+	"stockstat/roster"
+	"sync"
+)
+
+// 报告的种类
+const (
+	ReportIssues = "issues" // 每个问题一行
+	ReportStocks = "stocks" // 每只股票一行
+	ReportMarket = "market" // 全市场每个检查项一行
+)
+
+// Run 检查股票列表中各股票的数据文件, 把kind种报告中严重程度不低于min的部分写到out。
+// 有Error级别的问题时返回错误, 便于在脚本中使用。
+func Run(c *config.Config, out *cli.Output, opts *Options, kind string, min Severity) error {
+	var header []string
+	var records func(Severity) [][]string
+	r := NewReport()
+	switch kind {
+	case ReportIssues:
+		header, records = IssueHeader, r.IssueRecords
+	case ReportStocks:
+		header, records = StockHeader, r.StockRecords
+	case ReportMarket:
+		header, records = MarketHeader, r.MarketRecords
+	default:
+		return cli.Usagef("unknown report %q, want issues, stocks or market", kind)
+	}
+	stocks, err := roster.Load(c.RosterPath())
+	if err != nil {
+		return err
+	}
+	if opts.Calendar == nil {
+		if opts.Calendar, err = catalog.OpenCalendar(context.Background(), c.DataDir, c.Workers); err != nil {
+			return err
+		}
+	}
+	ctx := context.Background()
+	limit := c.Limiter()
+	var wg sync.WaitGroup
+	for _, code := range stocks.Codes() {
+		wg.Add(1)
+		go func(code string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			r.CheckFile(ctx, code, c.DataPath(code), opts)
+		}(code.String())
+	}
+	wg.Wait()
+	if err := out.WriteTable(header, records(min)); err != nil {
+		return err
+	}
+	bad := 0
+	for _, s := range r.Stocks {
+		if s.Worst() == Error {
+			bad++
+		}
+	}
+	if bad > 0 {
+		return fmt.Errorf("%d of %d stocks have errors", bad, len(r.Stocks))
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// package validate 检查行情数据的质量: 开高收低是否自洽, 日期是否升序且不重复,
+// 价格及成交量是否为正, 没有权值变化的异常涨跌, 以及过长的停牌间隔。
+package validate
+
+import (
+	"fmt"
+	"math"
+	"stockstat/calendar"
+	"stockstat/events"
+	"stockstat/readr"
+	"sync"
+)
+
+// Severity 是问题的严重程度
+type Severity int
+
+const (
+	Info    Severity = iota // 值得注意, 如长期停牌
+	Warning                 // 可能有错, 如异常涨跌
+	Error                   // 数据肯定有错, 如最高价低于最低价
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity 解析info, warning或error
+func ParseSeverity(s string) (Severity, error) {
+	for _, sev := range []Severity{Info, Warning, Error} {
+		if sev.String() == s {
+			return sev, nil
+		}
+	}
+	return Info, fmt.Errorf("unknown severity %q, want info, warning or error", s)
+}
+
+// 检查项的名字
+const (
+	CheckRead      = "read"      // 无法读取数据文件
+	CheckParse     = "parse"     // 无法解析的行
+	CheckDateOrder = "dateorder" // 日期不是升序
+	CheckDuplicate = "duplicate" // 日期重复
+	CheckOHLC      = "ohlc"      // 开收不在最高最低之间, 或最高低于最低
+	CheckPrice     = "price"     // 价格不为正
+	CheckVolume    = "volume"    // 成交量为负或为0
+	CheckJump      = "jump"      // 没有权值变化的异常涨跌
+	CheckGap       = "gap"       // 相邻两行相隔过多交易日
+)
+
+// Issue 是发现的一个问题
+type Issue struct {
+	Code     string // 股票代码
+	Row      int    // 在Frame中的行号, 从0开始; 解析错误时为文件中的行号
+	Date     string
+	Check    string
+	Severity Severity
+	Message  string
+}
+
+// Options 是检查的参数, 零值使用缺省值
+type Options struct {
+	// MaxJump 是权值不变时相邻两日收盘价允许的最大涨跌幅, 缺省为DefaultMaxJump
+	MaxJump float64
+	// MaxGap 是相邻两行之间允许缺少的交易日数, 缺省为DefaultMaxGap
+	MaxGap int
+	// Calendar 用于计算缺少的交易日数, 为nil时使用calendar.Default()
+	Calendar *calendar.Calendar
+}
+
+// 缺省参数
+const (
+	DefaultMaxJump = 0.35 // 涨跌停最大为北交所的30%
+	DefaultMaxGap  = 10
+)
+
+func (opts *Options) maxJump() float64 {
+	if opts == nil || opts.MaxJump <= 0 {
+		return DefaultMaxJump
+	}
+	return opts.MaxJump
+}
+
+var (
+	defaultCalendar     *calendar.Calendar
+	defaultCalendarOnce sync.Once
+)
+
+func (opts *Options) calendar() *calendar.Calendar {
+	if opts != nil && opts.Calendar != nil {
+		return opts.Calendar
+	}
+	defaultCalendarOnce.Do(func() { defaultCalendar = calendar.Default() })
+	return defaultCalendar
+}
+
+func (opts *Options) maxGap() int {
+	if opts == nil || opts.MaxGap <= 0 {
+		return DefaultMaxGap
+	}
+	return opts.MaxGap
+}
+
+// Check 检查股票code的数据frame, 按行号顺序返回发现的问题
+func Check(code string, frame *readr.Frame, opts *Options) []Issue {
+	var issues []Issue
+	add := func(i int, check string, sev Severity, format string, args ...interface{}) {
+		issues = append(issues, Issue{code, i, frame.Dates[i], check, sev, fmt.Sprintf(format, args...)})
+	}
+	hasPower := frame.Has(readr.ColPower)
+	maxJump, maxGap, cal := opts.maxJump(), opts.maxGap(), opts.calendar()
+	for i := range frame.Dates {
+		o, h, c, l := frame.Opens[i], frame.Highs[i], frame.Closes[i], frame.Lows[i]
+
+		bad := false
+		for _, p := range []float64{o, h, c, l} {
+			if !(p > 0) || math.IsInf(p, 0) { // 含NaN
+				bad = true
+			}
+		}
+		if bad {
+			add(i, CheckPrice, Error, "non-positive price: open %g high %g close %g low %g", o, h, c, l)
+		} else if h < l {
+			add(i, CheckOHLC, Error, "high %g < low %g", h, l)
+		} else if o > h || o < l || c > h || c < l {
+			add(i, CheckOHLC, Error, "open %g or close %g outside [%g, %g]", o, c, l, h)
+		}
+
+		if v := frame.Volumns[i]; v < 0 || math.IsNaN(v) {
+			add(i, CheckVolume, Error, "negative volume %g", v)
+		} else if v == 0 {
+			add(i, CheckVolume, Warning, "zero volume")
+		}
+
+		if i == 0 {
+			continue
+		}
+		prev, cur := frame.Times[i-1], frame.Times[i]
+		switch {
+		case cur.Equal(prev):
+			add(i, CheckDuplicate, Error, "duplicated date")
+			continue
+		case cur.Before(prev):
+			add(i, CheckDateOrder, Error, "date before %s", frame.Dates[i-1])
+			continue
+		}
+		if n := cal.Between(prev, cur); n > maxGap {
+			add(i, CheckGap, Info, "%d trading days missing since %s", n, frame.Dates[i-1])
+		}
+
+		pc := frame.Closes[i-1]
+		if bad || !(pc > 0) {
+			continue
+		}
+		ratio := c / pc
+		powerChanged := false
+		if hasPower {
+			p0, p1 := frame.Power[i-1], frame.Power[i]
+			if p0 > 0 && p1 > 0 {
+				powerChanged = events.Changed(p0, p1)
+				ratio *= p1 / p0 // 复权后的涨跌
+			}
+		}
+		if math.Abs(ratio-1) > maxJump {
+			if powerChanged {
+				add(i, CheckJump, Warning, "close changed %+.1f%% after pow change", (ratio-1)*100)
+			} else {
+				add(i, CheckJump, Warning, "close changed %+.1f%% without pow change", (ratio-1)*100)
+			}
+		}
+	}
+	return issues
+}
+
+// ParseIssue 把宽松读取时跳过的行转换为Issue
+func ParseIssue(code string, e *readr.ParseError) Issue {
+	return Issue{Code: code, Row: e.Line, Check: CheckParse, Severity: Error, Message: e.Error()}
+}
@@ -0,0 +1,116 @@
+package validate
+
+import (
+	"stockstat/calendar"
+	"stockstat/readr"
+	"testing"
+)
+
+// bar 是测试用的一行数据
+type bar struct {
+	date                   string
+	open, high, close, low float64
+	volume, power          float64
+}
+
+// ok 返回date日价格为p, 权值为1的正常数据
+func ok(date string, p float64) bar {
+	return bar{date, p, p + 0.5, p, p - 0.5, 1000, 1}
+}
+
+func testFrame(bars ...bar) *readr.Frame {
+	frame := readr.NewFrame([]readr.Column{readr.ColOpen, readr.ColHigh, readr.ColClose, readr.ColLow, readr.ColVolumn, readr.ColPower}, len(bars))
+	for _, b := range bars {
+		t, err := readr.ParseDate(b.date)
+		if err != nil {
+			panic(err)
+		}
+		frame.Append(&readr.Bar{Date: b.date, Time: t, Open: b.open, High: b.high, Close: b.close, Low: b.low, Volumn: b.volume, Power: b.power})
+	}
+	return frame
+}
+
+func TestCheck(t *testing.T) {
+	type want struct {
+		row   int
+		check string
+		sev   Severity
+	}
+	tests := []struct {
+		name string
+		bars []bar
+		opts *Options
+		want []want
+	}{
+		{"clean", []bar{ok("2024-01-02", 10), ok("2024-01-03", 10.5), ok("2024-01-04", 10.2)}, nil, nil},
+		{"high below low", []bar{ok("2024-01-02", 10), {"2024-01-03", 10, 9.5, 10, 10.5, 1000, 1}}, nil,
+			[]want{{1, CheckOHLC, Error}}},
+		{"close above high", []bar{{"2024-01-02", 10, 10.5, 11, 9.5, 1000, 1}}, nil,
+			[]want{{0, CheckOHLC, Error}}},
+		{"open below low", []bar{{"2024-01-02", 9, 10.5, 10, 9.5, 1000, 1}}, nil,
+			[]want{{0, CheckOHLC, Error}}},
+		{"zero price", []bar{{"2024-01-02", 10, 10.5, 10, 0, 1000, 1}}, nil,
+			[]want{{0, CheckPrice, Error}}},
+		{"volume", []bar{{"2024-01-02", 10, 10.5, 10, 9.5, 0, 1}, {"2024-01-03", 10, 10.5, 10, 9.5, -1, 1}}, nil,
+			[]want{{0, CheckVolume, Warning}, {1, CheckVolume, Error}}},
+		{"duplicate date", []bar{ok("2024-01-02", 10), ok("2024-01-03", 10), ok("2024-01-03", 10)}, nil,
+			[]want{{2, CheckDuplicate, Error}}},
+		{"unsorted dates", []bar{ok("2024-01-02", 10), ok("2024-01-04", 10), ok("2024-01-03", 10)}, nil,
+			[]want{{2, CheckDateOrder, Error}}},
+		{"jump without pow change", []bar{ok("2024-01-02", 10), ok("2024-01-03", 5)}, nil,
+			[]want{{1, CheckJump, Warning}}},
+		{"ex-rights", []bar{ok("2024-01-02", 10), {"2024-01-03", 5, 5.5, 5, 4.5, 1000, 2}}, nil, nil},
+		{"jump after pow change", []bar{ok("2024-01-02", 10), {"2024-01-03", 9, 9.5, 9, 8.5, 1000, 2}}, nil,
+			[]want{{1, CheckJump, Warning}}},
+		{"small move", []bar{ok("2024-01-02", 10), ok("2024-01-03", 11)}, nil, nil},
+		{"gap", []bar{ok("2024-01-02", 10), ok("2024-02-01", 10)}, nil,
+			[]want{{1, CheckGap, Info}}},
+		{"gap allowed", []bar{ok("2024-01-02", 10), ok("2024-02-01", 10)}, &Options{MaxGap: 30}, nil},
+		{"spring festival", []bar{ok("2024-02-08", 10), ok("2024-02-19", 10)}, &Options{MaxGap: 1}, nil},
+		{"weekend", []bar{ok("2024-01-05", 10), ok("2024-01-08", 10)}, &Options{MaxGap: 1}, nil},
+	}
+	for _, tt := range tests {
+		opts := tt.opts
+		if opts == nil {
+			opts = &Options{}
+		}
+		opts.Calendar = calendar.Default()
+		issues := Check("600000", testFrame(tt.bars...), opts)
+		if len(issues) != len(tt.want) {
+			t.Errorf("%s: issues %+v, want %v", tt.name, issues, tt.want)
+			continue
+		}
+		for i, is := range issues {
+			w := tt.want[i]
+			if is.Row != w.row || is.Check != w.check || is.Severity != w.sev || is.Code != "600000" {
+				t.Errorf("%s: issue %d = %+v, want %v", tt.name, i, is, w)
+			}
+			if is.Date != tt.bars[is.Row].date {
+				t.Errorf("%s: issue %d date %s, want %s", tt.name, i, is.Date, tt.bars[is.Row].date)
+			}
+		}
+	}
+}
+
+func TestCheckMessages(t *testing.T) {
+	opts := &Options{Calendar: calendar.Default()}
+	issues := Check("600000", testFrame(ok("2024-01-02", 10), ok("2024-01-03", 5)), opts)
+	if len(issues) != 1 || issues[0].Message != "close changed -50.0% without pow change" {
+		t.Errorf("issues %+v", issues)
+	}
+	issues = Check("600000", testFrame(ok("2024-01-02", 10), ok("2024-02-01", 10)), opts)
+	if len(issues) != 1 || issues[0].Message != "21 trading days missing since 2024-01-02" {
+		t.Errorf("issues %+v", issues)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, sev := range []Severity{Info, Warning, Error} {
+		if got, err := ParseSeverity(sev.String()); got != sev || err != nil {
+			t.Errorf("ParseSeverity(%q) = %v, %v", sev, got, err)
+		}
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Error("ParseSeverity(fatal) succeeded")
+	}
+}